@@ -17,7 +17,10 @@
 package v1alpha1
 
 import (
+	admRegv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -32,7 +35,321 @@ type CertManagerSpec struct {
 	ImagePostFix  string `json:"imagePostFix,omitempty"`
 	Webhook       bool   `json:"enableWebhook,omitempty"`
 	ResourceNS    string `json:"resourceNamespace,omitempty"`
-	OCP311        bool   `json:"ocp311,omitempty"`
+
+	// ImagePullSecrets are the secrets the cert-manager images are pulled with. They aren't available
+	// to the acmesolver pods, which cert-manager creates in the namespaces of the challenges.
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// AcmesolverImage is the full reference, by tag or by digest, of the image cert-manager solves
+	// ACME HTTP-01 challenges with. It replaces the image built from imageRegistry.
+	AcmesolverImage string `json:"acmesolverImage,omitempty"`
+	// ImageMirrors rewrite the images, including the acmesolver image, to be pulled from mirrors.
+	// An image is rewritten by the mirror with the longest source it starts with.
+	ImageMirrors []ImageMirror `json:"imageMirrors,omitempty"`
+	// OCP311 runs the webhook on the pod network. Deprecated: the networking of the webhook is
+	// detected, webhook.hostNetwork overrides it.
+	OCP311 bool `json:"ocp311,omitempty"`
+
+	// Version is the cert-manager release to deploy. 0.10.3 serves the legacy certmanager.k8s.io/v1alpha1
+	// API and 1.5.4 serves the cert-manager.io/v1 API. Defaults to 0.10.3.
+	// +kubebuilder:validation:Enum="0.10.3";"1.5.4"
+	Version string `json:"version,omitempty"`
+
+	// PodPlacement is the default scheduling configuration of all cert-manager pods.
+	// Each field can be overridden by the component's own settings.
+	PodPlacement `json:",inline"`
+
+	// UninstallPolicy decides what is removed when the CertManager is deleted, one of
+	// Retain, DeleteOperands or DeleteAll. Defaults to DeleteOperands.
+	// +kubebuilder:validation:Enum=Retain;DeleteOperands;DeleteAll
+	UninstallPolicy UninstallPolicy `json:"uninstallPolicy,omitempty"`
+
+	// ManagementState is Managed for the operator to deploy and update cert-manager, or Unmanaged for it
+	// to leave everything as it is while still reporting the status. An Unmanaged CertManager is only
+	// uninstalled once it is Managed again. The paused annotation has the same effect. Defaults to Managed.
+	// +kubebuilder:validation:Enum=Managed;Unmanaged
+	ManagementState ManagementState `json:"managementState,omitempty"`
+
+	// HighAvailability runs two replicas of the controller, webhook and cainjector, spread across
+	// nodes and protected by PodDisruptionBudgets. A component's replicas overrides it.
+	HighAvailability bool `json:"highAvailability,omitempty"`
+
+	// Proxy is the HTTP proxy cert-manager-controller reaches ACME servers and other issuers through. It is also
+	// passed to the acmesolver pods. When it isn't set, the proxy of the OpenShift cluster is used. Setting it
+	// with no fields disables the proxy.
+	Proxy *ProxySpec `json:"proxy,omitempty"`
+
+	// ControllerConfig contains the settings for the cert-manager-controller
+	ControllerConfig ComponentSpec `json:"controller,omitempty"`
+	// WebhookConfig contains the settings for the cert-manager-webhook
+	WebhookConfig WebhookSpec `json:"webhook,omitempty"`
+	// CainjectorConfig contains the settings for the cert-manager-cainjector
+	CainjectorConfig ComponentSpec `json:"cainjector,omitempty"`
+	// ConfigmapWatcherConfig contains the settings for the configmap-watcher
+	ConfigmapWatcherConfig ComponentSpec `json:"configmapWatcher,omitempty"`
+
+	// Migration configures the migration of certmanager.k8s.io resources to cert-manager.io
+	Migration MigrationSpec `json:"migration,omitempty"`
+
+	// DefaultIssuers are clusterissuers the operator creates so that certificates can be requested
+	// as soon as cert-manager is deployed. They are removed when this is unset.
+	DefaultIssuers *DefaultIssuersSpec `json:"defaultIssuers,omitempty"`
+
+	// Metrics configures the metrics the operator exports and the monitoring of cert-manager
+	Metrics MetricsSpec `json:"metrics,omitempty"`
+
+	// Overrides are patches applied in order to the objects the operator renders, before they are compared
+	// with the objects in the cluster, so that the operator keeps the patched result. Patches to fields the
+	// operator doesn't compare are only applied when the object is created or updated for another change.
+	// Overrides that can't be applied are ignored and reported in the OverridesApplied condition.
+	Overrides []Override `json:"overrides,omitempty"`
+}
+
+// OverrideType is how an override patches its object
+type OverrideType string
+
+const (
+	// OverrideStrategicMerge patches the object with a strategic merge patch
+	OverrideStrategicMerge OverrideType = "strategic"
+	// OverrideJSONPatch patches the object with a JSON patch
+	OverrideJSONPatch OverrideType = "json"
+)
+
+// Override is a patch of an object the operator renders
+type Override struct {
+	// Kind of the patched object, one of Deployment, Service, ClusterRole, ClusterRoleBinding, RoleBinding,
+	// APIService, MutatingWebhookConfiguration or ValidatingWebhookConfiguration
+	// +kubebuilder:validation:Enum=Deployment;Service;ClusterRole;ClusterRoleBinding;RoleBinding;APIService;MutatingWebhookConfiguration;ValidatingWebhookConfiguration
+	Kind string `json:"kind"`
+	// Name of the patched object, such as cert-manager-webhook
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Type of the patch, strategic or json. Defaults to strategic.
+	// +kubebuilder:validation:Enum=strategic;json
+	Type OverrideType `json:"type,omitempty"`
+	// Patch is the patch in YAML or JSON, an object for a strategic merge patch and a list of operations for a JSON patch
+	// +kubebuilder:validation:MinLength=1
+	Patch string `json:"patch"`
+}
+
+// MetricsSpec configures the metrics the operator exports and the monitoring of cert-manager
+type MetricsSpec struct {
+	// Certificates exports the expiry, renewal time, readiness and issuer of every certificate
+	// of the deployed cert-manager release, and counts their failed issuances
+	Certificates bool `json:"certificates,omitempty"`
+	// Monitoring exposes the metrics of cert-manager-controller through a service, with a ServiceMonitor
+	// for Prometheus to scrape them and a PrometheusRule alerting on certificates and the webhook.
+	// It is only done when the cluster serves the Prometheus operator's monitoring.coreos.com/v1 API.
+	Monitoring bool `json:"monitoring,omitempty"`
+}
+
+// DefaultIssuersSpec configures a self-signed root CA with a CA clusterissuer signing with it,
+// and optionally ACME clusterissuers. The secrets they use are in the cluster resource namespace.
+type DefaultIssuersSpec struct {
+	// CA configures the root CA and its clusterissuer
+	CA DefaultCASpec `json:"ca,omitempty"`
+	// ACME are the ACME clusterissuers to create
+	ACME []ACMEIssuerSpec `json:"acme,omitempty"`
+}
+
+// DefaultCASpec configures the root CA, which is issued by the cs-ss-issuer self-signed clusterissuer
+// into the cs-ca-certificate-secret secret
+type DefaultCASpec struct {
+	// IssuerName is the name of the CA clusterissuer. Defaults to cs-ca-issuer.
+	IssuerName string `json:"issuerName,omitempty"`
+	// CommonName is the common name of the root CA certificate. Defaults to cs-ca-certificate.
+	CommonName string `json:"commonName,omitempty"`
+	// Duration is how long the root CA certificate is valid for. Defaults to 2 years.
+	Duration *metav1.Duration `json:"duration,omitempty"`
+}
+
+// ACMEIssuerSpec is an ACME clusterissuer
+type ACMEIssuerSpec struct {
+	// Name is the name of the clusterissuer
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Server is the URL of the ACME server's directory
+	// +kubebuilder:validation:MinLength=1
+	Server string `json:"server"`
+	// Email is the address the ACME account is registered with
+	Email string `json:"email,omitempty"`
+	// PrivateKeySecretName is the secret holding the private key of the ACME account.
+	// cert-manager generates the key when the secret doesn't exist.
+	// +kubebuilder:validation:MinLength=1
+	PrivateKeySecretName string `json:"privateKeySecretName"`
+	// ExternalAccountBinding binds the ACME account to an account with the CA. It is only
+	// supported by cert-manager 1.5.4.
+	ExternalAccountBinding *ACMEExternalAccountBinding `json:"externalAccountBinding,omitempty"`
+	// Solvers are the cert-manager solvers of the clusterissuer, passed through as they are.
+	// Defaults to an HTTP-01 solver through an ingress.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Solvers []runtime.RawExtension `json:"solvers,omitempty"`
+}
+
+// ACMEExternalAccountBinding is the key of an account with the CA that an ACME account is bound to
+type ACMEExternalAccountBinding struct {
+	// KeyID is the ID of the account's key
+	// +kubebuilder:validation:MinLength=1
+	KeyID string `json:"keyID"`
+	// KeySecretRef is the key in a secret holding the account's base64url encoded HMAC key
+	KeySecretRef corev1.SecretKeySelector `json:"keySecretRef"`
+}
+
+// MigrationSpec configures the migration of the legacy certmanager.k8s.io certificates,
+// issuers and clusterissuers into their cert-manager.io equivalents
+type MigrationSpec struct {
+	// Enabled converts the existing legacy objects into cert-manager.io objects.
+	// The legacy objects are kept, as are the secrets issued for the certificates.
+	Enabled bool `json:"enabled,omitempty"`
+	// DryRun only reports what would be migrated, without changing anything
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// UninstallPolicy decides what is removed when the CertManager is deleted
+type UninstallPolicy string
+
+const (
+	// UninstallRetain leaves cert-manager running and keeps all of its resources, which are no longer managed by the operator
+	UninstallRetain UninstallPolicy = "Retain"
+	// UninstallDeleteOperands removes the cert-manager services, but keeps the CRDs along with the certificates and issuers
+	UninstallDeleteOperands UninstallPolicy = "DeleteOperands"
+	// UninstallDeleteAll removes the cert-manager services and the CRDs, which deletes all certificates and issuers
+	UninstallDeleteAll UninstallPolicy = "DeleteAll"
+)
+
+// ManagementState decides whether the operator manages cert-manager
+type ManagementState string
+
+const (
+	// Managed has the operator create, update and remove cert-manager's resources
+	Managed ManagementState = "Managed"
+	// Unmanaged has the operator leave cert-manager's resources as they are, only reporting their status
+	Unmanaged ManagementState = "Unmanaged"
+)
+
+// PausedAnnotation set to "true" on the CertManager stops the operator from managing cert-manager,
+// as if its managementState was Unmanaged, without changing its spec
+const PausedAnnotation = "certmanager.operators.ibm.com/paused"
+
+// Paused returns true when the CertManager is Unmanaged or annotated as paused
+func (c *CertManager) Paused() bool {
+	return c.Spec.ManagementState == Unmanaged || c.Annotations[PausedAnnotation] == "true"
+}
+
+// ComponentSpec defines the settings that can be customized for a single cert-manager component
+type ComponentSpec struct {
+	// Replicas is the number of pods of the component. The configmap-watcher has no
+	// leader election and always runs a single replica.
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Image is the full reference, by tag or by digest, of the component's image. It replaces
+	// the image built from imageRegistry and imagePostFix.
+	Image string `json:"image,omitempty"`
+	// ImagePullPolicy overrides the pull policy of the component's image
+	// +kubebuilder:validation:Enum=Always;IfNotPresent;Never
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// ExtraArgs are appended to the args of the component's container, each as a single --flag=value.
+	// The verbosity, --v, replaces the operator's. Other flags the operator sets are rejected.
+	ExtraArgs []string `json:"extraArgs,omitempty"`
+	// Env is added to the environment of the component's container. Variables the operator sets are rejected.
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Resources overrides the default compute resources of the component's container.
+	// Only the limits and requests that are set replace the defaults. A request above a default limit raises it.
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// LivenessProbe overrides the timings of the component's liveness probe, if it has one
+	LivenessProbe *ProbeTimings `json:"livenessProbe,omitempty"`
+	// ReadinessProbe overrides the timings of the component's readiness probe, if it has one
+	ReadinessProbe *ProbeTimings `json:"readinessProbe,omitempty"`
+
+	// PodPlacement overrides the global scheduling configuration for the component's pods
+	PodPlacement `json:",inline"`
+}
+
+// ImageMirror replaces the source at the start of an image reference with the mirror.
+// The source is a registry, a repository or a repository's namespace, such as quay.io/opencloudio.
+type ImageMirror struct {
+	// Source is the prefix of the images that are mirrored
+	// +kubebuilder:validation:MinLength=1
+	Source string `json:"source"`
+	// Mirror is the prefix the source is replaced with
+	// +kubebuilder:validation:MinLength=1
+	Mirror string `json:"mirror"`
+}
+
+// ProxySpec is the HTTP proxy outbound connections are made through. It is passed to
+// cert-manager-controller and the acmesolver pods as the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables. cert-manager can't set the environment of the acmesolver pods, so the
+// operator's webhook adds the variables to them when they are created.
+type ProxySpec struct {
+	// HTTPProxy is the URL of the proxy for HTTP requests
+	HTTPProxy string `json:"httpProxy,omitempty"`
+	// HTTPSProxy is the URL of the proxy for HTTPS requests
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	// NoProxy is a comma separated list of hosts, domains and CIDRs that are reached without the proxy.
+	// The API server is always added to it.
+	NoProxy string `json:"noProxy,omitempty"`
+}
+
+// ProbeTimings are the timings of a probe. Only the timings that are set replace the defaults.
+type ProbeTimings struct {
+	// InitialDelaySeconds is how long after the container starts the probe is first run
+	// +kubebuilder:validation:Minimum=0
+	InitialDelaySeconds *int32 `json:"initialDelaySeconds,omitempty"`
+	// PeriodSeconds is how often the probe is run
+	// +kubebuilder:validation:Minimum=1
+	PeriodSeconds *int32 `json:"periodSeconds,omitempty"`
+	// TimeoutSeconds is how long the probe waits for an answer
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+	// FailureThreshold is how many times in a row the probe has to fail for it to be considered failed
+	// +kubebuilder:validation:Minimum=1
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
+}
+
+// WebhookSpec defines the settings of the cert-manager-webhook and of the webhook configurations
+// that send the cert-manager resources to it
+type WebhookSpec struct {
+	ComponentSpec `json:",inline"`
+
+	// HostNetwork runs the webhook on the host network when true and on the pod network when false.
+	// When unset, the webhook runs on the pod network, and moves to the host network once the API server
+	// was found not to reach it there. It always runs on the pod network of OpenShift 3.11.
+	HostNetwork *bool `json:"hostNetwork,omitempty"`
+
+	// FailurePolicy decides whether requests are rejected (Fail) or let through (Ignore) when the
+	// webhook can't be reached. Defaults to Fail.
+	// +kubebuilder:validation:Enum=Fail;Ignore
+	FailurePolicy *admRegv1beta1.FailurePolicyType `json:"failurePolicy,omitempty"`
+	// TimeoutSeconds is how long the API server waits for the webhook before applying the failure policy
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=30
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+	// NamespaceSelector is added to the webhooks' own namespace selector, both must match
+	// a namespace for its resources to be sent to the webhook
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// ObjectSelector must match the labels of a resource for it to be sent to the webhook
+	ObjectSelector *metav1.LabelSelector `json:"objectSelector,omitempty"`
+	// ExcludedNamespaces are namespaces whose resources are never sent to the webhook, such as
+	// kube-system. They are matched on the kubernetes.io/metadata.name label, which the operator
+	// sets on them on clusters older than Kubernetes 1.21.
+	ExcludedNamespaces []string `json:"excludedNamespaces,omitempty"`
+}
+
+// PodPlacement defines where the cert-manager pods are scheduled
+type PodPlacement struct {
+	// NodeSelector must match a node's labels for the pods to be scheduled on that node
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations allow the pods to be scheduled onto nodes with matching taints
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Affinity is the node affinity and pod affinity/anti-affinity of the pods
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// TopologySpreadConstraints describes how the pods are spread across topology domains
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// PriorityClassName is the name of the priority class the pods run with
+	PriorityClassName string `json:"priorityClassName,omitempty"`
 }
 
 // CertManagerStatus defines the observed state of CertManager
@@ -41,6 +358,176 @@ type CertManagerStatus struct {
 	// +operator-sdk:gen-csv:customresourcedefinitions.statusDescriptors=true
 	// +operator-sdk:gen-csv:customresourcedefinitions.statusDescriptors.displayName="CertManager Status"
 	OverallStatus string `json:"certManagerStatus"`
+	// ObservedGeneration is the most recent generation of the CertManager that has been reconciled
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions are the latest available observations of the cert-manager service's state
+	// +operator-sdk:gen-csv:customresourcedefinitions.statusDescriptors=true
+	// +operator-sdk:gen-csv:customresourcedefinitions.statusDescriptors.x-descriptors="urn:alm:descriptor:io.kubernetes.conditions"
+	Conditions []CertManagerCondition `json:"conditions,omitempty"`
+	// Components is the health of each cert-manager component, read from its deployment
+	Components []ComponentStatus `json:"components,omitempty"`
+	// Migration is the outcome of the last migration of certmanager.k8s.io resources
+	Migration *MigrationStatus `json:"migration,omitempty"`
+	// AcmesolverImage is the image cert-manager-controller solves ACME HTTP-01 challenges with
+	AcmesolverImage string `json:"acmesolverImage,omitempty"`
+	// Platform is the platform detected for the cluster and the choices made for it
+	Platform *PlatformStatus `json:"platform,omitempty"`
+	// Proxy is the proxy cert-manager-controller and the acmesolver pods are configured with, unset when there is none
+	Proxy *ProxyStatus `json:"proxy,omitempty"`
+	// DefaultIssuers is the readiness of the default issuers and of the root CA certificate
+	DefaultIssuers []DefaultIssuerStatus `json:"defaultIssuers,omitempty"`
+}
+
+// DefaultIssuerStatus is the readiness of a default issuer or of the root CA certificate
+type DefaultIssuerStatus struct {
+	// Kind of the object, ClusterIssuer or Certificate
+	Kind string `json:"kind"`
+	// Name of the object
+	Name string `json:"name"`
+	// Ready is the status of the object's Ready condition, Unknown until cert-manager sets it
+	Ready corev1.ConditionStatus `json:"ready"`
+	// Message explains why the object isn't ready
+	Message string `json:"message,omitempty"`
+}
+
+// ProxySource is where the proxy settings were read from
+type ProxySource string
+
+const (
+	// ProxySourceCertManager is the proxy set in the CertManager's spec
+	ProxySourceCertManager ProxySource = "CertManager"
+	// ProxySourceCluster is the proxy of the OpenShift cluster
+	ProxySourceCluster ProxySource = "Cluster"
+)
+
+// ProxyStatus is the proxy cert-manager-controller and the acmesolver pods are configured with and where it was read from
+type ProxyStatus struct {
+	// Source is where the proxy was read from, CertManager or Cluster
+	Source    ProxySource `json:"source"`
+	ProxySpec `json:",inline"`
+}
+
+// PlatformType is the kind of cluster cert-manager is deployed on
+type PlatformType string
+
+const (
+	// PlatformKubernetes is any cluster that isn't OpenShift
+	PlatformKubernetes PlatformType = "Kubernetes"
+	// PlatformOpenShift is an OpenShift cluster
+	PlatformOpenShift PlatformType = "OpenShift"
+)
+
+// PlatformStatus is the platform detected for the cluster
+type PlatformStatus struct {
+	// Type of the platform, Kubernetes or OpenShift
+	Type PlatformType `json:"type"`
+	// OpenShiftVersion is the version of OpenShift, empty if it can't be read, as on OpenShift 3
+	OpenShiftVersion string `json:"openShiftVersion,omitempty"`
+	// KubernetesVersion is the version of the API server
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+	// SecurityContextConstraints is true when the cluster serves the OpenShift security context constraints API
+	SecurityContextConstraints bool `json:"securityContextConstraints,omitempty"`
+	// APIServerReachesPods is whether the API server was found to reach the webhook on the pod network,
+	// unset until it is known. It is read from the availability of the legacy webhook's apiservice,
+	// and from a dry run request sent to the v1 webhook.
+	APIServerReachesPods *bool `json:"apiServerReachesPods,omitempty"`
+	// WebhookHostNetwork is true when the webhook runs on the host network
+	WebhookHostNetwork bool `json:"webhookHostNetwork,omitempty"`
+}
+
+// MigrationStatus is the outcome of migrating the certmanager.k8s.io resources
+type MigrationStatus struct {
+	// DryRun is true if nothing was changed by the migration
+	DryRun bool `json:"dryRun,omitempty"`
+	// Migrated is the number of objects that were migrated, or that would be in a dry run
+	Migrated int32 `json:"migrated"`
+	// Failed is the number of objects that could not be migrated
+	Failed int32 `json:"failed"`
+	// Skipped is the number of objects whose name is taken by a cert-manager.io object the migration didn't create
+	Skipped int32 `json:"skipped"`
+	// Objects is the outcome of the migration for the objects that failed or were skipped, and for those
+	// that would be migrated in a dry run. Failures are listed first, and at most 100 objects are listed.
+	Objects []MigrationObjectStatus `json:"objects,omitempty"`
+}
+
+// MigrationState is the outcome of migrating a single object
+type MigrationState string
+
+const (
+	// MigrationMigrated means the cert-manager.io object was created from the legacy object
+	MigrationMigrated MigrationState = "Migrated"
+	// MigrationWouldMigrate means the object would be migrated, it is only reported in a dry run
+	MigrationWouldMigrate MigrationState = "WouldMigrate"
+	// MigrationSkipped means a cert-manager.io object of the same name that wasn't created by the migration already exists
+	MigrationSkipped MigrationState = "Skipped"
+	// MigrationFailed means the object could not be migrated
+	MigrationFailed MigrationState = "Failed"
+)
+
+// MigrationObjectStatus is the outcome of migrating a single legacy object
+type MigrationObjectStatus struct {
+	// Kind of the legacy object
+	Kind string `json:"kind"`
+	// Namespace of the legacy object, empty for clusterissuers
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the legacy object
+	Name string `json:"name"`
+	// State is the outcome of migrating the object
+	State MigrationState `json:"state"`
+	// Message explains the state, such as why the object could not be migrated
+	Message string `json:"message,omitempty"`
+}
+
+// ConditionType is the type of a CertManager condition
+type ConditionType string
+
+const (
+	// ConditionAvailable is true when every deployed cert-manager component has all of its replicas ready
+	ConditionAvailable ConditionType = "Available"
+	// ConditionProgressing is true while a cert-manager component is rolling out
+	ConditionProgressing ConditionType = "Progressing"
+	// ConditionDegraded is true when the last reconcile of the CertManager failed or an update to a CRD was refused
+	ConditionDegraded ConditionType = "Degraded"
+	// ConditionPrereqsMet is true when the CRDs and RBAC cert-manager needs are in place
+	ConditionPrereqsMet ConditionType = "PrereqsMet"
+	// ConditionCRDsUpToDate is false when updating a CRD to its bundled definition was refused because it could lose data
+	ConditionCRDsUpToDate ConditionType = "CRDsUpToDate"
+	// ConditionPaused is true while the CertManager is Unmanaged or annotated as paused
+	ConditionPaused ConditionType = "Paused"
+	// ConditionOverridesApplied is false when one or more of the overrides can't be applied to their objects
+	ConditionOverridesApplied ConditionType = "OverridesApplied"
+)
+
+// CertManagerCondition describes the state of the cert-manager service at a certain point
+type CertManagerCondition struct {
+	// Type of the condition
+	Type ConditionType `json:"type"`
+	// Status of the condition, one of True, False, Unknown
+	Status corev1.ConditionStatus `json:"status"`
+	// ObservedGeneration is the generation of the CertManager the condition was set for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastTransitionTime is the last time the condition's status changed
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason is a one word, CamelCase reason for the condition's last transition
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable message with details about the last transition
+	Message string `json:"message,omitempty"`
+}
+
+// ComponentStatus is the observed state of a single cert-manager component
+type ComponentStatus struct {
+	// Name of the component
+	Name string `json:"name"`
+	// Deployment is the name of the component's deployment
+	Deployment string `json:"deployment"`
+	// Image is the image the component's deployment runs
+	Image string `json:"image,omitempty"`
+	// DesiredReplicas is the number of replicas requested for the component
+	DesiredReplicas int32 `json:"desiredReplicas"`
+	// ReadyReplicas is the number of the component's replicas that are ready
+	ReadyReplicas int32 `json:"readyReplicas"`
+	// LastError is the last error met while deploying the component
+	LastError string `json:"lastError,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -0,0 +1,118 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	admRegv1 "k8s.io/api/admissionregistration/v1"
+	admRegv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// admissionClient reads and writes webhook configurations in the newest admissionregistration version the
+// API server serves. The configurations are handled as v1beta1 objects, which have the same fields as
+// v1 and are converted to v1 when the API server serves it.
+type admissionClient struct {
+	client client.Client
+	v1     bool
+}
+
+// Returns an admissionClient for the API server the discovery client points at
+func newAdmissionClient(client client.Client, discovery discovery.DiscoveryInterface) *admissionClient {
+	// admissionregistration.k8s.io/v1 replaces v1beta1 from Kubernetes 1.16 on
+	return &admissionClient{client: client, v1: servesGroupVersion(discovery, admRegv1.SchemeGroupVersion.String())}
+}
+
+// Returns the object in the version the API server serves
+func (a *admissionClient) served(obj runtime.Object) (runtime.Object, error) {
+	if !a.v1 {
+		return obj, nil
+	}
+	var out runtime.Object
+	switch obj.(type) {
+	case *admRegv1beta1.MutatingWebhookConfiguration:
+		out = &admRegv1.MutatingWebhookConfiguration{}
+	case *admRegv1beta1.ValidatingWebhookConfiguration:
+		out = &admRegv1.ValidatingWebhookConfiguration{}
+	default:
+		return nil, fmt.Errorf("Error converting %T to admissionregistration v1", obj)
+	}
+	if err := convertAdmission(obj, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Copies the fields of one admissionregistration version into another. The versions share their fields,
+// only the API server's defaults differ, which are left to it.
+func convertAdmission(in, out runtime.Object) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return err
+	}
+	// The type is left for the client to fill in from the go type
+	out.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{})
+	return nil
+}
+
+func (a *admissionClient) get(key types.NamespacedName, obj runtime.Object) error {
+	served, err := a.served(obj)
+	if err != nil {
+		return err
+	}
+	if err := a.client.Get(context.Background(), key, served); err != nil {
+		return err
+	}
+	if served == obj {
+		return nil
+	}
+	return convertAdmission(served, obj)
+}
+
+func (a *admissionClient) create(obj runtime.Object) error {
+	served, err := a.served(obj)
+	if err != nil {
+		return err
+	}
+	return a.client.Create(context.Background(), served)
+}
+
+func (a *admissionClient) update(obj runtime.Object) error {
+	served, err := a.served(obj)
+	if err != nil {
+		return err
+	}
+	return a.client.Update(context.Background(), served)
+}
+
+func (a *admissionClient) delete(obj runtime.Object) error {
+	served, err := a.served(obj)
+	if err != nil {
+		return err
+	}
+	return a.client.Delete(context.Background(), served)
+}
@@ -18,20 +18,23 @@ package certmanager
 
 import (
 	"context"
-	"reflect"
+	goerrors "errors"
 
 	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
 	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
 
+	monclientv1 "github.com/coreos/prometheus-operator/pkg/client/versioned/typed/monitoring/v1"
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	admRegv1beta1 "k8s.io/api/admissionregistration/v1beta1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	rbacv1 "k8s.io/api/rbac/v1"
-	apiextensionsAPIv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
-	apiextensionclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
 	apiRegv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
@@ -59,8 +62,9 @@ func Add(mgr manager.Manager) error {
 
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	apiextclient, _ := apiextensionclientset.NewForConfig(mgr.GetConfig())
 	kubeclient, _ := kubernetes.NewForConfig(mgr.GetConfig())
+	dynclient, _ := dynamic.NewForConfig(mgr.GetConfig())
+	monclient, _ := monclientv1.NewForConfig(mgr.GetConfig())
 	ns, _ := k8sutil.GetWatchNamespace()
 
 	if ns == "" {
@@ -68,12 +72,15 @@ func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 	}
 
 	return &ReconcileCertManager{
-		client:       mgr.GetClient(),
-		kubeclient:   kubeclient,
-		apiextclient: apiextclient,
-		scheme:       mgr.GetScheme(),
-		recorder:     mgr.GetEventRecorderFor("ibm-cert-manager-operator"),
-		ns:           ns,
+		client:     mgr.GetClient(),
+		kubeclient: kubeclient,
+		crdclient:  newCRDClient(mgr.GetConfig()),
+		admission:  newAdmissionClient(mgr.GetClient(), kubeclient.Discovery()),
+		dynclient:  dynclient,
+		monclient:  monclient,
+		scheme:     mgr.GetScheme(),
+		recorder:   mgr.GetEventRecorderFor("ibm-cert-manager-operator"),
+		ns:         ns,
 	}
 }
 
@@ -118,6 +125,15 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watch for changes to secondary resource RoleBindings and requeue the owner CertManager
+	err = c.Watch(&source.Kind{Type: &rbacv1.RoleBinding{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &operatorv1alpha1.CertManager{},
+	})
+	if err != nil {
+		return err
+	}
+
 	// Watch for changes to secondary resource ServiceAccounts and requeue the owner CertManager
 	err = c.Watch(&source.Kind{Type: &corev1.ServiceAccount{}}, &handler.EnqueueRequestForOwner{
 		IsController: true,
@@ -127,8 +143,9 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
-	// Watch changes to custom resource defintions that are owned by this operator - in case of deletion or changes
-	err = c.Watch(&source.Kind{Type: &apiextensionsAPIv1beta1.CustomResourceDefinition{}}, &handler.EnqueueRequestForOwner{
+	// Watch changes to custom resource defintions that are owned by this operator - in case of deletion or changes.
+	// They are watched in the apiextensions version they are served as.
+	err = c.Watch(&source.Kind{Type: newCRDClient(mgr.GetConfig()).object()}, &handler.EnqueueRequestForOwner{
 		IsController: true,
 		OwnerType:    &operatorv1alpha1.CertManager{},
 	})
@@ -136,8 +153,13 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
-	// Watch changes to mutating webhook configuration that are owned by this operator - in case of deletion or changes
-	err = c.Watch(&source.Kind{Type: &admRegv1beta1.MutatingWebhookConfiguration{}}, &handler.EnqueueRequestForOwner{
+	// Watch changes to mutating webhook configuration that are owned by this operator - in case of deletion or changes.
+	// They are watched in the admissionregistration version they are served as.
+	kubeclient, _ := kubernetes.NewForConfig(mgr.GetConfig())
+	admission := newAdmissionClient(mgr.GetClient(), kubeclient.Discovery())
+	mutating, _ := admission.served(&admRegv1beta1.MutatingWebhookConfiguration{})
+	validating, _ := admission.served(&admRegv1beta1.ValidatingWebhookConfiguration{})
+	err = c.Watch(&source.Kind{Type: mutating}, &handler.EnqueueRequestForOwner{
 		IsController: true,
 		OwnerType:    &operatorv1alpha1.CertManager{},
 	})
@@ -145,7 +167,7 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 	// Watch changes to validating webhook configuration that are owned by this operator - in case of deletion or changes
-	err = c.Watch(&source.Kind{Type: &admRegv1beta1.ValidatingWebhookConfiguration{}}, &handler.EnqueueRequestForOwner{
+	err = c.Watch(&source.Kind{Type: validating}, &handler.EnqueueRequestForOwner{
 		IsController: true,
 		OwnerType:    &operatorv1alpha1.CertManager{},
 	})
@@ -168,6 +190,43 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	if err != nil {
 		return err
 	}
+	// Watch changes to the OpenShift cluster's proxy, which cert-manager-controller is configured with
+	if servesGroupVersion(kubeclient.Discovery(), clusterProxyGVR.GroupVersion().String()) {
+		clusterProxy := &unstructured.Unstructured{}
+		clusterProxy.SetGroupVersionKind(clusterProxyGVR.GroupVersion().WithKind("Proxy"))
+		err = c.Watch(&source.Kind{Type: clusterProxy}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(func(object handler.MapObject) []reconcile.Request {
+				if object.Meta.GetName() != clusterProxyName {
+					return nil
+				}
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "default"}}}
+			}),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// Watch for namespaces created without their name label, which excluded namespaces are labelled with
+	// on clusters older than Kubernetes 1.21
+	err = c.Watch(&source.Kind{Type: &corev1.Namespace{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(object handler.MapObject) []reconcile.Request {
+			if _, ok := object.Meta.GetLabels()[namespaceNameLabel]; ok {
+				return nil
+			}
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "default"}}}
+		}),
+	})
+	if err != nil {
+		return err
+	}
+	// Watch changes to pod disruption budgets that are owned by this operator - in case of deletion or changes
+	err = c.Watch(&source.Kind{Type: &policyv1beta1.PodDisruptionBudget{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &operatorv1alpha1.CertManager{},
+	})
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -178,12 +237,15 @@ var _ reconcile.Reconciler = &ReconcileCertManager{}
 type ReconcileCertManager struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client       client.Client
-	kubeclient   kubernetes.Interface
-	apiextclient apiextensionclientset.Interface
-	scheme       *runtime.Scheme
-	recorder     record.EventRecorder
-	ns           string
+	client     client.Client
+	kubeclient kubernetes.Interface
+	crdclient  *crdClient
+	admission  *admissionClient
+	dynclient  dynamic.Interface
+	monclient  monclientv1.MonitoringV1Interface
+	scheme     *runtime.Scheme
+	recorder   record.EventRecorder
+	ns         string
 }
 
 // Reconcile reads that state of the cluster for a CertManager object and makes changes based on the state read
@@ -216,6 +278,18 @@ func (r *ReconcileCertManager) Reconcile(request reconcile.Request) (reconcile.R
 		return reconcile.Result{}, nil
 	}
 
+	if r.paused(instance) {
+		return reconcile.Result{}, nil
+	}
+
+	bundle, err := res.GetBundle(instance.Spec.Version)
+	if err != nil {
+		log.Error(err, "Unsupported cert-manager version")
+		r.updateStatus(instance, nil, nil, err, nil)
+		r.updateEvent(instance, err.Error(), corev1.EventTypeWarning, "UnsupportedVersion")
+		return reconcile.Result{}, nil
+	}
+
 	finalizerName := "certmanager.operators.ibm.com"
 	// Determine if the certmanager crd is going to be deleted
 	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -230,6 +304,12 @@ func (r *ReconcileCertManager) Reconcile(request reconcile.Request) (reconcile.R
 	} else {
 		// Object scheduled to be deleted
 		if containsString(instance.ObjectMeta.Finalizers, finalizerName) {
+			if err := r.uninstall(instance, bundle); err != nil {
+				log.Error(err, "Error uninstalling cert-manager, requeueing")
+				r.updateEvent(instance, err.Error(), corev1.EventTypeWarning, "UninstallFailed")
+				return reconcile.Result{Requeue: true}, nil
+			}
+			r.updateEvent(instance, "Uninstalled cert-manager", corev1.EventTypeNormal, "Uninstalled")
 			instance.ObjectMeta.Finalizers = removeString(instance.ObjectMeta.Finalizers, finalizerName)
 			if err := r.client.Update(context.Background(), instance); err != nil {
 				log.Error(err, "Error updating the CR to remove the finalizer")
@@ -240,78 +320,124 @@ func (r *ReconcileCertManager) Reconcile(request reconcile.Request) (reconcile.R
 		return reconcile.Result{}, err
 	}
 
-	log.Info("The namespace", "ns", r.ns)
+	log.Info("The namespace", "ns", r.ns, "cert-manager version", bundle.Version)
 	r.updateEvent(instance, "Instance found", corev1.EventTypeNormal, "Initializing")
 
+	// Overrides that can't be applied are skipped, so that they don't stop cert-manager from being deployed
+	overrideErrs := validateOverrides(instance, bundle, r.ns)
+	if len(overrideErrs) > 0 {
+		log.Info("Ignoring overrides that can't be applied", "errors", overrideErrs.ToAggregate().Error())
+		r.updateEvent(instance, overrideErrs.ToAggregate().Error(), corev1.EventTypeWarning, "InvalidOverrides")
+	}
+
 	// Check Prerequisites
-	if err := r.PreReqs(instance); err != nil {
-		log.Error(err, "One or more prerequisites not met, requeueing")
-		r.updateStatus(instance, "Error deploying cert-manager, prereqs not met")
-		r.updateEvent(instance, err.Error(), corev1.EventTypeWarning, "PrereqsFailed")
-		return reconcile.Result{Requeue: true}, nil
+	var crdErr error
+	if err := r.PreReqs(instance, bundle); err != nil {
+		var refused *crdUpdateRefusedError
+		if !goerrors.As(err, &refused) {
+			log.Error(err, "One or more prerequisites not met, requeueing")
+			r.updateStatus(instance, overrideErrs, nil, err, nil)
+			r.updateEvent(instance, err.Error(), corev1.EventTypeWarning, "PrereqsFailed")
+			return reconcile.Result{Requeue: true}, nil
+		}
+		// The existing CRDs still work, so cert-manager is deployed regardless
+		crdErr = err
+		r.updateEvent(instance, err.Error(), corev1.EventTypeWarning, "CRDUpdateRefused")
+	} else {
+		r.updateEvent(instance, "All prerequisites for deploying cert-manager service found", corev1.EventTypeNormal, "PrereqsMet")
+	}
+
+	// The webhook's networking depends on the platform, which is detected on every reconcile
+	// because whether the API server reaches the pods is only known once the webhook is running
+	if err := r.detectPlatform(instance, bundle); err != nil {
+		log.Error(err, "Error recording the detected platform")
+	}
+	if err := r.detectProxy(instance); err != nil {
+		log.Error(err, "Error recording the proxy")
 	}
-	r.updateEvent(instance, "All prerequisites for deploying cert-manager service found", corev1.EventTypeNormal, "PrereqsMet")
 
 	// Check Deployment itself
-	if err := r.deployments(instance); err != nil {
+	if err := r.deployments(instance, bundle); err != nil {
 		log.Error(err, "Error with deploying cert-manager, requeueing")
 		r.updateEvent(instance, err.Error(), corev1.EventTypeWarning, "Failed")
-		r.updateStatus(instance, "Error deploying cert-manager")
+		r.updateStatus(instance, overrideErrs, crdErr, nil, err)
 		return reconcile.Result{Requeue: true}, nil
 	}
 	r.updateEvent(instance, "Deployed cert-manager successfully", corev1.EventTypeNormal, "Deployed")
-	r.updateStatus(instance, "Successfully deployed cert-manager")
 
+	if err := r.monitoring(instance, bundle); err != nil {
+		log.Error(err, "Error setting up the monitoring of cert-manager")
+		r.updateEvent(instance, err.Error(), corev1.EventTypeWarning, "MonitoringFailed")
+	}
+
+	issuers, err := r.defaultIssuers(instance, bundle)
+	if err != nil {
+		r.updateEvent(instance, err.Error(), corev1.EventTypeWarning, "DefaultIssuersFailed")
+	}
+	instance.Status.DefaultIssuers = issuers
+	r.updateStatus(instance, overrideErrs, crdErr, nil, nil)
+
+	// cert-manager's objects aren't watched, since their CRDs may not exist when the operator starts
+	if err != nil || !defaultIssuersReady(issuers) {
+		return reconcile.Result{RequeueAfter: defaultIssuersRetryInterval}, nil
+	}
 	return reconcile.Result{}, nil
 }
 
-func (r *ReconcileCertManager) PreReqs(instance *operatorv1alpha1.CertManager) error {
-	if err := checkCrds(instance, r.scheme, r.apiextclient.ApiextensionsV1beta1().CustomResourceDefinitions(), instance.Name, instance.Namespace); err != nil {
+func (r *ReconcileCertManager) PreReqs(instance *operatorv1alpha1.CertManager, bundle *res.Bundle) error {
+	crdErr := checkCrds(instance, r.scheme, r.crdclient, r.dynclient, r.recorder, bundle)
+	var refused *crdUpdateRefusedError
+	if crdErr != nil && !goerrors.As(crdErr, &refused) {
 		log.V(2).Info("Checking CRDs failed")
-		return err
+		return crdErr
 	}
-	if err := checkRbac(instance, r.scheme, r.client, r.ns); err != nil {
+	if err := checkRbac(instance, r.scheme, r.client, r.recorder, bundle, r.ns); err != nil {
 		log.V(2).Info("Checking RBAC failed")
 		return err
 	}
-	return nil
+	return crdErr
 }
 
-func (r *ReconcileCertManager) deployments(instance *operatorv1alpha1.CertManager) error {
-	if err := certManagerDeploy(instance, r.client, r.kubeclient, r.scheme, r.ns); err != nil {
-		return err
+func (r *ReconcileCertManager) deployments(instance *operatorv1alpha1.CertManager, bundle *res.Bundle) error {
+	if err := certManagerDeploy(instance, r.client, r.kubeclient, r.scheme, bundle, r.ns); err != nil {
+		return &componentError{deployment: res.CertManagerControllerName, err: err}
 	}
 
-	if err := configmapWatcherDeploy(instance, r.client, r.kubeclient, r.scheme, r.ns); err != nil {
-		return err
+	if err := configmapWatcherDeploy(instance, r.client, r.kubeclient, r.scheme, bundle, r.ns); err != nil {
+		return &componentError{deployment: res.ConfigmapWatcherName, err: err}
 	}
 
 	if instance.Spec.Webhook {
 		// Check webhook prerequisites
-		if err := webhookPrereqs(instance, r.scheme, r.client, r.ns); err != nil {
+		if err := webhookPrereqs(instance, r.scheme, r.client, r.admission, r.recorder, bundle, r.ns); err != nil {
 			return err
 		}
 		// Deploy webhook and cainjector
-		if err := cainjectorDeploy(instance, r.client, r.kubeclient, r.scheme, r.ns); err != nil {
-			return err
+		if err := cainjectorDeploy(instance, r.client, r.kubeclient, r.scheme, bundle, r.ns); err != nil {
+			return &componentError{deployment: res.CertManagerCainjectorName, err: err}
 		}
-		if err := webhookDeploy(instance, r.client, r.kubeclient, r.scheme, r.ns); err != nil {
-			return err
+		if err := webhookDeploy(instance, r.client, r.kubeclient, r.scheme, bundle, r.ns); err != nil {
+			return &componentError{deployment: res.CertManagerWebhookName, err: err}
 		}
 	} else {
 		// Specified to not deploy the webhook, remove them if they exist
-		webhook := removeDeploy(r.kubeclient, res.CertManagerWebhookName, res.DeployNamespace)
-		cainjector := removeDeploy(r.kubeclient, res.CertManagerCainjectorName, res.DeployNamespace)
-		if !errors.IsNotFound(webhook) {
+		webhook := removeDeploy(r.kubeclient, res.CertManagerWebhookName, r.ns)
+		cainjector := removeDeploy(r.kubeclient, res.CertManagerCainjectorName, r.ns)
+		if webhook != nil && !errors.IsNotFound(webhook) {
 			log.Error(webhook, "error removing webhook")
 			return webhook
 		}
-		if !errors.IsNotFound(cainjector) {
-			log.Error(cainjector, "error removing webhook")
+		if cainjector != nil && !errors.IsNotFound(cainjector) {
+			log.Error(cainjector, "error removing cainjector")
 			return cainjector
 		}
+		for _, name := range []string{res.CertManagerWebhookName, res.CertManagerCainjectorName} {
+			if err := removeDisruptionBudget(r.client, name, r.ns); err != nil {
+				return err
+			}
+		}
 		// Remove webhook prerequisites
-		if err := removeWebhookPrereqs(r.client, r.ns); err != nil {
+		if err := removeWebhookPrereqs(r.client, r.admission, r.ns); err != nil {
 			return err
 		}
 	}
@@ -321,12 +447,3 @@ func (r *ReconcileCertManager) deployments(instance *operatorv1alpha1.CertManage
 func (r *ReconcileCertManager) updateEvent(instance *operatorv1alpha1.CertManager, message, event, reason string) {
 	r.recorder.Event(instance, event, reason, message)
 }
-
-func (r *ReconcileCertManager) updateStatus(instance *operatorv1alpha1.CertManager, message string) {
-	if !reflect.DeepEqual(instance.Status.OverallStatus, message) {
-		instance.Status.OverallStatus = message
-		if err := r.client.Status().Update(context.TODO(), instance); err != nil {
-			log.Error(err, "Error updating instance status")
-		}
-	}
-}
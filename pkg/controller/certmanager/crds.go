@@ -0,0 +1,289 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"fmt"
+	"strings"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/install"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apiextensionclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// crdScheme converts CRDs between the apiextensions versions and the internal types
+var crdScheme = runtime.NewScheme()
+
+func init() {
+	install.Install(crdScheme)
+}
+
+// crdClient reads and writes CRDs in the newest apiextensions version the API server serves.
+// The CRDs are handled in the internal apiextensions types, which both versions convert to.
+type crdClient struct {
+	client apiextensionclientset.Interface
+	v1     bool
+}
+
+// Returns a crdClient for the API server the config points at
+func newCRDClient(config *rest.Config) *crdClient {
+	client, _ := apiextensionclientset.NewForConfig(config)
+	// apiextensions.k8s.io/v1 replaces v1beta1 from Kubernetes 1.16 on
+	return &crdClient{client: client, v1: servesGroupVersion(client.Discovery(), apiextv1.SchemeGroupVersion.String())}
+}
+
+// Returns an empty CRD of the version the client uses, to be read with the controller-runtime client
+func (c *crdClient) object() runtime.Object {
+	if c.v1 {
+		return &apiextv1.CustomResourceDefinition{}
+	}
+	return &apiextv1beta1.CustomResourceDefinition{}
+}
+
+func (c *crdClient) list() ([]apiext.CustomResourceDefinition, error) {
+	var list runtime.Object
+	var err error
+	if c.v1 {
+		list, err = c.client.ApiextensionsV1().CustomResourceDefinitions().List(metav1.ListOptions{})
+	} else {
+		list, err = c.client.ApiextensionsV1beta1().CustomResourceDefinitions().List(metav1.ListOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	crds := &apiext.CustomResourceDefinitionList{}
+	if err := crdScheme.Convert(list, crds, nil); err != nil {
+		return nil, err
+	}
+	return crds.Items, nil
+}
+
+func (c *crdClient) create(crd *apiext.CustomResourceDefinition) error {
+	if c.v1 {
+		out := &apiextv1.CustomResourceDefinition{}
+		if err := crdScheme.Convert(crd, out, nil); err != nil {
+			return err
+		}
+		_, err := c.client.ApiextensionsV1().CustomResourceDefinitions().Create(out)
+		return err
+	}
+	out := &apiextv1beta1.CustomResourceDefinition{}
+	if err := crdScheme.Convert(crd, out, nil); err != nil {
+		return err
+	}
+	_, err := c.client.ApiextensionsV1beta1().CustomResourceDefinitions().Create(out)
+	return err
+}
+
+func (c *crdClient) update(crd *apiext.CustomResourceDefinition) error {
+	if c.v1 {
+		out := &apiextv1.CustomResourceDefinition{}
+		if err := crdScheme.Convert(crd, out, nil); err != nil {
+			return err
+		}
+		_, err := c.client.ApiextensionsV1().CustomResourceDefinitions().Update(out)
+		return err
+	}
+	out := &apiextv1beta1.CustomResourceDefinition{}
+	if err := crdScheme.Convert(crd, out, nil); err != nil {
+		return err
+	}
+	_, err := c.client.ApiextensionsV1beta1().CustomResourceDefinitions().Update(out)
+	return err
+}
+
+// Converts a bundled CRD to the internal types, laid out as the API server returns it in the version
+// the client uses. Served as v1, the printer columns, subresources and schema move into each version.
+func (c *crdClient) desired(crd *apiextv1beta1.CustomResourceDefinition) (*apiext.CustomResourceDefinition, error) {
+	desired := &apiext.CustomResourceDefinition{}
+	if err := crdScheme.Convert(crd, desired, nil); err != nil {
+		return nil, err
+	}
+	if !c.v1 {
+		return desired, nil
+	}
+	v1 := &apiextv1.CustomResourceDefinition{}
+	if err := crdScheme.Convert(desired, v1, nil); err != nil {
+		return nil, err
+	}
+	crdScheme.Default(v1)
+	desired = &apiext.CustomResourceDefinition{}
+	if err := crdScheme.Convert(v1, desired, nil); err != nil {
+		return nil, err
+	}
+	return desired, nil
+}
+
+// crdUpdateRefusedError is returned when updating one or more CRDs was refused because
+// the update could lose data. The existing CRDs keep working, so it isn't fatal.
+type crdUpdateRefusedError struct {
+	reasons []string
+}
+
+func (e *crdUpdateRefusedError) Error() string {
+	return "Refused to update CRDs: " + strings.Join(e.reasons, "; ")
+}
+
+// Returns the desired spec with the fields the API server defaults filled in from the live
+// spec, so that they aren't seen as a difference
+func crdSpecDefaults(desired, live apiext.CustomResourceDefinitionSpec) apiext.CustomResourceDefinitionSpec {
+	spec := *desired.DeepCopy()
+	if spec.Version == "" {
+		spec.Version = live.Version
+	}
+	if spec.Versions == nil {
+		spec.Versions = live.Versions
+	}
+	if spec.Names.Singular == "" {
+		spec.Names.Singular = live.Names.Singular
+	}
+	if spec.Names.ListKind == "" {
+		spec.Names.ListKind = live.Names.ListKind
+	}
+	if spec.Conversion == nil {
+		spec.Conversion = live.Conversion
+	}
+	if spec.PreserveUnknownFields == nil {
+		spec.PreserveUnknownFields = live.PreserveUnknownFields
+	}
+	return spec
+}
+
+// Returns the reasons updating the live CRD to the desired spec could lose data, which is when
+// a version that objects are stored in would be dropped or a field that is still set on an
+// object would be removed from the schema
+func crdUpdateRisks(dynclient dynamic.Interface, live *apiext.CustomResourceDefinition, desired apiext.CustomResourceDefinitionSpec) ([]string, error) {
+	var risks []string
+	versions := map[string]bool{desired.Version: true}
+	for _, version := range desired.Versions {
+		versions[version.Name] = true
+	}
+	for _, stored := range live.Status.StoredVersions {
+		if !versions[stored] {
+			risks = append(risks, fmt.Sprintf("CRD %s would drop version %s, which objects are stored in", live.Name, stored))
+		}
+	}
+
+	// Each version can have its own schema, the fields removed from any of them are checked
+	var removed [][]string
+	seen := make(map[string]bool)
+	for _, version := range live.Spec.Versions {
+		for _, path := range removedFields(versionSchema(live.Spec, version.Name), versionSchema(desired, version.Name), nil) {
+			if key := strings.Join(path, "."); !seen[key] {
+				seen[key] = true
+				removed = append(removed, path)
+			}
+		}
+	}
+	if len(removed) == 0 {
+		return risks, nil
+	}
+	gvr := schema.GroupVersionResource{Group: live.Spec.Group, Version: live.Spec.Version, Resource: live.Spec.Names.Plural}
+	list, err := dynclient.Resource(gvr).List(metav1.ListOptions{})
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return risks, nil
+		}
+		return nil, err
+	}
+	for _, path := range removed {
+		for _, item := range list.Items {
+			if fieldInUse(item.Object, path) {
+				risks = append(risks, fmt.Sprintf("CRD %s would remove field %s, which is set on %s %s",
+					live.Name, strings.Join(path, "."), item.GetKind(), objectName(item.GetNamespace(), item.GetName())))
+				break
+			}
+		}
+	}
+	return risks, nil
+}
+
+// Returns the schema of the version, which is the CRD's schema unless the versions have their own
+func versionSchema(spec apiext.CustomResourceDefinitionSpec, version string) *apiext.JSONSchemaProps {
+	if spec.Validation != nil {
+		return spec.Validation.OpenAPIV3Schema
+	}
+	for _, v := range spec.Versions {
+		if v.Name == version && v.Schema != nil {
+			return v.Schema.OpenAPIV3Schema
+		}
+	}
+	return nil
+}
+
+// Returns the paths of the properties in the live schema that are missing from the desired
+// schema. Array items are represented by "[]" in the paths. Nothing below a property that
+// preserves unknown fields counts as removed.
+func removedFields(live, desired *apiext.JSONSchemaProps, path []string) [][]string {
+	if live == nil || desired == nil {
+		return nil
+	}
+	if desired.XPreserveUnknownFields != nil && *desired.XPreserveUnknownFields {
+		return nil
+	}
+	var removed [][]string
+	for name, prop := range live.Properties {
+		propPath := append(append([]string{}, path...), name)
+		desiredProp, ok := desired.Properties[name]
+		if !ok {
+			removed = append(removed, propPath)
+			continue
+		}
+		removed = append(removed, removedFields(&prop, &desiredProp, propPath)...)
+	}
+	if live.Items != nil && desired.Items != nil {
+		removed = append(removed, removedFields(live.Items.Schema, desired.Items.Schema, append(append([]string{}, path...), "[]"))...)
+	}
+	return removed
+}
+
+// Returns true if the object has a value at the path
+func fieldInUse(obj interface{}, path []string) bool {
+	if len(path) == 0 {
+		return true
+	}
+	switch value := obj.(type) {
+	case map[string]interface{}:
+		child, ok := value[path[0]]
+		return ok && fieldInUse(child, path[1:])
+	case []interface{}:
+		if path[0] != "[]" {
+			return false
+		}
+		for _, item := range value {
+			if fieldInUse(item, path[1:]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func objectName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
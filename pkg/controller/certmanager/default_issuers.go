@@ -0,0 +1,286 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"fmt"
+	"time"
+
+	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
+	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// How long to wait before checking again on default issuers that aren't ready
+const defaultIssuersRetryInterval = 30 * time.Second
+
+// The default ACME solver answers HTTP-01 challenges through an ingress of the default class
+var defaultACMESolvers = []interface{}{
+	map[string]interface{}{"http01": map[string]interface{}{"ingress": map[string]interface{}{}}},
+}
+
+// Returns the cluster resource namespace, where cert-manager reads the secrets of clusterissuers from
+func clusterResourceNamespace(instance *operatorv1alpha1.CertManager) string {
+	if instance.Spec.ResourceNS != "" {
+		return instance.Spec.ResourceNS
+	}
+	return res.DeployNamespace
+}
+
+// Returns the issuers and certificate the operator creates for the instance's default issuers
+func desiredDefaultIssuers(instance *operatorv1alpha1.CertManager, bundle *res.Bundle) ([]*unstructured.Unstructured, error) {
+	spec := instance.Spec.DefaultIssuers
+	if spec == nil {
+		return nil, nil
+	}
+	issuerName := spec.CA.IssuerName
+	if issuerName == "" {
+		issuerName = res.CAIssuerName
+	}
+	commonName := spec.CA.CommonName
+	if commonName == "" {
+		commonName = res.CACertificateName
+	}
+	duration := res.DefaultCADuration
+	if spec.CA.Duration != nil {
+		duration = *spec.CA.Duration
+	}
+	desired := []*unstructured.Unstructured{
+		res.SelfSignedIssuer(bundle),
+		res.CACertificate(bundle, clusterResourceNamespace(instance), commonName, duration),
+		res.CAIssuer(bundle, issuerName),
+	}
+
+	for _, issuer := range spec.ACME {
+		acme := map[string]interface{}{
+			"server":              issuer.Server,
+			"privateKeySecretRef": map[string]interface{}{"name": issuer.PrivateKeySecretName},
+			"solvers":             defaultACMESolvers,
+		}
+		if issuer.Email != "" {
+			acme["email"] = issuer.Email
+		}
+		if binding := issuer.ExternalAccountBinding; binding != nil {
+			// cert-manager 1.5.4 requires the algorithm, which every CA uses HS256 for
+			acme["externalAccountBinding"] = map[string]interface{}{
+				"keyID":        binding.KeyID,
+				"keyAlgorithm": "HS256",
+				"keySecretRef": map[string]interface{}{"name": binding.KeySecretRef.Name, "key": binding.KeySecretRef.Key},
+			}
+		}
+		if len(issuer.Solvers) > 0 {
+			// The solvers are decoded the way the API server returns them, with whole numbers as integers
+			var solvers []interface{}
+			for i, raw := range issuer.Solvers {
+				var solver interface{}
+				if err := json.Unmarshal(raw.Raw, &solver); err != nil {
+					return nil, fmt.Errorf("Error decoding solver %d of ACME issuer %s: %v", i, issuer.Name, err)
+				}
+				solvers = append(solvers, solver)
+			}
+			acme["solvers"] = solvers
+		}
+		desired = append(desired, res.ACMEIssuer(bundle, issuer.Name, acme))
+	}
+	return desired, nil
+}
+
+// Creates and updates the instance's default issuers, removes the ones it no longer has and returns their
+// readiness. The issuers are checked by cert-manager's webhook, so they can only be created once it is running.
+func (r *ReconcileCertManager) defaultIssuers(instance *operatorv1alpha1.CertManager, bundle *res.Bundle) ([]operatorv1alpha1.DefaultIssuerStatus, error) {
+	desired, err := desiredDefaultIssuers(instance, bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []operatorv1alpha1.DefaultIssuerStatus
+	var firstErr error
+	keep := make(map[string]bool, len(desired))
+	for _, obj := range desired {
+		keep[defaultIssuerKey(obj)] = true
+		status, err := r.defaultIssuer(instance, obj)
+		if err != nil {
+			log.Error(err, "Error reconciling default issuer", "kind", obj.GetKind(), "name", obj.GetName())
+			status = operatorv1alpha1.DefaultIssuerStatus{Kind: obj.GetKind(), Name: obj.GetName(), Ready: corev1.ConditionUnknown, Message: err.Error()}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	if err := r.removeDefaultIssuers(instance, bundle, keep); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return statuses, firstErr
+}
+
+// Creates or updates a single default issuer or certificate and returns its readiness. An object of the
+// same name that the operator didn't create is left alone, unless it is one of its own that was orphaned.
+func (r *ReconcileCertManager) defaultIssuer(instance *operatorv1alpha1.CertManager, desired *unstructured.Unstructured) (operatorv1alpha1.DefaultIssuerStatus, error) {
+	status := operatorv1alpha1.DefaultIssuerStatus{Kind: desired.GetKind(), Name: desired.GetName()}
+	if err := controllerutil.SetControllerReference(instance, desired, r.scheme); err != nil {
+		return status, err
+	}
+	client := r.dynclient.Resource(defaultIssuerGVR(desired)).Namespace(desired.GetNamespace())
+
+	existing, err := client.Get(desired.GetName(), metav1.GetOptions{})
+	if err != nil && apiErrors.IsNotFound(err) {
+		log.V(1).Info("Creating default issuer", "kind", desired.GetKind(), "name", desired.GetName())
+		if _, err := client.Create(desired, metav1.CreateOptions{}); err != nil {
+			return status, err
+		}
+		status.Ready = corev1.ConditionUnknown
+		status.Message = "Created, waiting for cert-manager to report its readiness"
+		return status, nil
+	} else if err != nil {
+		return status, err
+	}
+
+	owner := metav1.GetControllerOf(existing)
+	if existing.GetLabels()[res.DefaultIssuerLabel] != "true" || (owner != nil && owner.UID != instance.UID) {
+		status.Ready = corev1.ConditionFalse
+		status.Message = fmt.Sprintf("A %s named %s that isn't managed by the operator already exists", desired.GetKind(), desired.GetName())
+		return status, nil
+	}
+	if owner == nil || !equality.Semantic.DeepEqual(existing.Object["spec"], desired.Object["spec"]) {
+		log.V(1).Info("Default issuer has drifted, updating it", "kind", desired.GetKind(), "name", desired.GetName())
+		existing.Object["spec"] = desired.Object["spec"]
+		if owner == nil {
+			existing.SetOwnerReferences(append(existing.GetOwnerReferences(), desired.GetOwnerReferences()...))
+		}
+		if existing, err = client.Update(existing, metav1.UpdateOptions{}); err != nil {
+			return status, err
+		}
+	}
+	status.Ready, status.Message = readyCondition(existing)
+	return status, nil
+}
+
+// Removes the default issuers the instance created that aren't kept. They are looked up
+// in every namespace, since the certificate moves along with the cluster resource namespace.
+func (r *ReconcileCertManager) removeDefaultIssuers(instance *operatorv1alpha1.CertManager, bundle *res.Bundle, keep map[string]bool) error {
+	for _, kind := range []string{res.ClusterIssuerKind, res.CertificateKind} {
+		list, err := r.listDefaultIssuers(bundle, kind)
+		if err != nil {
+			return err
+		}
+		for i := range list {
+			obj := &list[i]
+			if keep[defaultIssuerKey(obj)] || !metav1.IsControlledBy(obj, instance) {
+				continue
+			}
+			log.V(1).Info("Removing default issuer", "kind", kind, "name", obj.GetName(), "namespace", obj.GetNamespace())
+			err := r.dynclient.Resource(defaultIssuerGVR(obj)).Namespace(obj.GetNamespace()).Delete(obj.GetName(), &metav1.DeleteOptions{})
+			if err != nil && !apiErrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Removes the instance's owner reference from its default issuers, so that they aren't garbage collected with it
+func (r *ReconcileCertManager) orphanDefaultIssuers(instance *operatorv1alpha1.CertManager, bundle *res.Bundle) error {
+	for _, kind := range []string{res.ClusterIssuerKind, res.CertificateKind} {
+		list, err := r.listDefaultIssuers(bundle, kind)
+		if err != nil {
+			return err
+		}
+		for i := range list {
+			obj := &list[i]
+			var refs []metav1.OwnerReference
+			for _, ref := range obj.GetOwnerReferences() {
+				if ref.UID != instance.UID {
+					refs = append(refs, ref)
+				}
+			}
+			if len(refs) == len(obj.GetOwnerReferences()) {
+				continue
+			}
+			obj.SetOwnerReferences(refs)
+			log.V(1).Info("Removing owner reference", "kind", kind, "name", obj.GetName(), "namespace", obj.GetNamespace())
+			if _, err := r.dynclient.Resource(defaultIssuerGVR(obj)).Namespace(obj.GetNamespace()).Update(obj, metav1.UpdateOptions{}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Lists the default issuers of the kind in every namespace. Nothing is listed when the kind's CRD isn't installed.
+func (r *ReconcileCertManager) listDefaultIssuers(bundle *res.Bundle, kind string) ([]unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(bundle.Group + "/" + bundle.APIVersion)
+	obj.SetKind(kind)
+	list, err := r.dynclient.Resource(defaultIssuerGVR(obj)).List(metav1.ListOptions{LabelSelector: res.DefaultIssuerLabel + "=true"})
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func defaultIssuerGVR(obj *unstructured.Unstructured) schema.GroupVersionResource {
+	gvk := obj.GroupVersionKind()
+	resource := "clusterissuers"
+	if gvk.Kind == res.CertificateKind {
+		resource = "certificates"
+	}
+	return gvk.GroupVersion().WithResource(resource)
+}
+
+func defaultIssuerKey(obj *unstructured.Unstructured) string {
+	return obj.GetKind() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// Returns the status and message of the object's Ready condition
+func readyCondition(obj *unstructured.Unstructured) (corev1.ConditionStatus, string) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, item := range conditions {
+		condition, ok := item.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" {
+			continue
+		}
+		status, _ := condition["status"].(string)
+		message, _ := condition["message"].(string)
+		if status == string(corev1.ConditionTrue) {
+			return corev1.ConditionTrue, ""
+		}
+		return corev1.ConditionStatus(status), message
+	}
+	return corev1.ConditionUnknown, "Waiting for cert-manager to report its readiness"
+}
+
+// Returns true if all of the default issuers are ready
+func defaultIssuersReady(statuses []operatorv1alpha1.DefaultIssuerStatus) bool {
+	for _, status := range statuses {
+		if status.Ready != corev1.ConditionTrue {
+			return false
+		}
+	}
+	return true
+}
@@ -28,6 +28,7 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
@@ -36,25 +37,26 @@ import (
 )
 
 // Returns true if no errors in deploy logic
-func certManagerDeploy(instance *operatorv1alpha1.CertManager, client client.Client, kubeclient kubernetes.Interface, scheme *runtime.Scheme, ns string) error {
-	return deployLogic(instance, client, kubeclient, scheme, res.ControllerDeployment, res.CertManagerControllerName, res.ControllerImageName, res.ControllerLabels, ns)
+func certManagerDeploy(instance *operatorv1alpha1.CertManager, client client.Client, kubeclient kubernetes.Interface, scheme *runtime.Scheme, bundle *res.Bundle, ns string) error {
+	return deployLogic(instance, client, kubeclient, scheme, bundle, bundle.ControllerDeployment(ns), res.CertManagerControllerName, bundle.ControllerImageName, res.ControllerLabels, ns)
 }
 
-func cainjectorDeploy(instance *operatorv1alpha1.CertManager, client client.Client, kubeclient kubernetes.Interface, scheme *runtime.Scheme, ns string) error {
-	return deployLogic(instance, client, kubeclient, scheme, res.CainjectorDeployment, res.CertManagerCainjectorName, res.CainjectorImageName, res.CainjectorLabels, ns)
+func cainjectorDeploy(instance *operatorv1alpha1.CertManager, client client.Client, kubeclient kubernetes.Interface, scheme *runtime.Scheme, bundle *res.Bundle, ns string) error {
+	return deployLogic(instance, client, kubeclient, scheme, bundle, bundle.CainjectorDeployment(ns), res.CertManagerCainjectorName, bundle.CainjectorImageName, res.CainjectorLabels, ns)
 }
 
-func webhookDeploy(instance *operatorv1alpha1.CertManager, client client.Client, kubeclient kubernetes.Interface, scheme *runtime.Scheme, ns string) error {
-	return deployLogic(instance, client, kubeclient, scheme, res.WebhookDeployment, res.CertManagerWebhookName, res.WebhookImageName, res.WebhookLabels, ns)
+func webhookDeploy(instance *operatorv1alpha1.CertManager, client client.Client, kubeclient kubernetes.Interface, scheme *runtime.Scheme, bundle *res.Bundle, ns string) error {
+	return deployLogic(instance, client, kubeclient, scheme, bundle, bundle.WebhookDeployment(ns), res.CertManagerWebhookName, bundle.WebhookImageName, res.WebhookLabels, ns)
 }
 
-func configmapWatcherDeploy(instance *operatorv1alpha1.CertManager, client client.Client, kubeclient kubernetes.Interface, scheme *runtime.Scheme, ns string) error {
-	return deployLogic(instance, client, kubeclient, scheme, res.ConfigmapWatcherDeployment, res.ConfigmapWatcherName, res.ConfigmapWatcherImageName, res.ConfigmapWatcherLabels, ns)
+func configmapWatcherDeploy(instance *operatorv1alpha1.CertManager, client client.Client, kubeclient kubernetes.Interface, scheme *runtime.Scheme, bundle *res.Bundle, ns string) error {
+	return deployLogic(instance, client, kubeclient, scheme, bundle, res.ConfigmapWatcherDeployment(ns), res.ConfigmapWatcherName, res.ConfigmapWatcherImageName, res.ConfigmapWatcherLabels, ns)
 }
 
-func deployLogic(instance *operatorv1alpha1.CertManager, client client.Client, kubeclient kubernetes.Interface, scheme *runtime.Scheme, deployTemplate *appsv1.Deployment, name, imageName, labels, ns string) error {
+func deployLogic(instance *operatorv1alpha1.CertManager, client client.Client, kubeclient kubernetes.Interface, scheme *runtime.Scheme, bundle *res.Bundle, deploy *appsv1.Deployment, name, imageName, labels, ns string) error {
 	similarDeploys := deployFinder(kubeclient, labels, imageName)
-	deployment := setupDeploy(instance, deployTemplate, ns)
+	deployment := setupDeploy(instance, bundle, deploy, ns)
+	applyOverrides(instance, "Deployment", name, &deployment)
 	var existingDeploy appsv1.Deployment
 	create := true
 
@@ -95,6 +97,9 @@ func deployLogic(instance *operatorv1alpha1.CertManager, client client.Client, k
 			log.V(3).Info("Deploys are equal, no changes needed")
 		}
 	}
+	if err := disruptionBudget(instance, client, scheme, &deployment); err != nil {
+		return err
+	}
 	log.V(2).Info("Finished working on deploy logic", "deployment name", name)
 	return nil
 }
@@ -102,55 +107,255 @@ func deployLogic(instance *operatorv1alpha1.CertManager, client client.Client, k
 // Configure deployment options
 // Args:deploy
 //     instance - The CR instance of CertManager
-//     deploy - The base deployment object - template contains most of the defaults/constants for the deployment
-func setupDeploy(instance *operatorv1alpha1.CertManager, deploy *appsv1.Deployment, ns string) appsv1.Deployment {
-	// First copy the deploy template into a deployment object
-
+//     bundle - The cert-manager release being deployed
+//     deploy - The base deployment object built by the resources package - contains most of the defaults/constants for the deployment
+func setupDeploy(instance *operatorv1alpha1.CertManager, bundle *res.Bundle, deploy *appsv1.Deployment, ns string) appsv1.Deployment {
+	// The base deployment is built fresh for every reconcile, so it can be modified directly
 	returningDeploy := *deploy
 
-	imageRegistry := res.ImageRegistry
+	// The configmap-watcher isn't part of a cert-manager release, so it is always pulled from the operator's own registry
+	imageRegistry := bundle.ImageRegistry
+	watcherRegistry := res.ImageRegistry
 	if instance.Spec.ImageRegistry != "" {
 		imageRegistry = strings.TrimRight(instance.Spec.ImageRegistry, "/")
+		watcherRegistry = imageRegistry
 	}
+	postFix := instance.Spec.ImagePostFix
+	var component operatorv1alpha1.ComponentSpec
+	var image string
 	switch deploy.Name {
 	case res.CertManagerControllerName:
-		returningDeploy.Spec.Template.Spec.Containers[0].Image = imageRegistry + "/" + res.ControllerImageName + ":" + res.ControllerImageVersion
-		var acmesolver = "--acme-http01-solver-image=" + imageRegistry + "/" + res.AcmesolverImageName + ":" + res.ControllerImageVersion
+		component = instance.Spec.ControllerConfig
+		image = componentImage(component.Image, imageRegistry, bundle.ControllerImageName, bundle.ImageTag, postFix)
+		// The acmesolver has never had the postfix appended
+		var acmesolver = mirrorImage(componentImage(instance.Spec.AcmesolverImage, imageRegistry, bundle.AcmesolverImageName, bundle.ImageTag, ""), instance.Spec.ImageMirrors)
 
-		var resourceNS = res.ResourceNS
+		var resourceNS = res.DeployNamespace
 		if instance.Spec.ResourceNS != "" {
-			resourceNS = "--cluster-resource-namespace=" + instance.Spec.ResourceNS
+			resourceNS = instance.Spec.ResourceNS
 		}
-		var leaderElect = "--leader-election-namespace=" + ns
-		var webhookNS = "--webhook-namespace=" + ns
-		var webhookDNS = "--webhook-dns-names=cert-manager-webhook,cert-manager-webhook." + ns + ",cert-manager-webhook." + ns + ".svc"
-		var args = make([]string, len(res.DefaultArgs))
-		copy(args, res.DefaultArgs)
-		args = append(args, acmesolver, resourceNS, leaderElect, webhookNS, webhookDNS)
-		returningDeploy.Spec.Template.Spec.Containers[0].Args = args
+		returningDeploy.Spec.Template.Spec.Containers[0].Args = bundle.ControllerArgs(ns, resourceNS, acmesolver)
 		log.V(3).Info("The args", "args", deploy.Spec.Template.Spec.Containers[0].Args)
+		returningDeploy.Spec.Template.Spec.Containers[0].Env = append(returningDeploy.Spec.Template.Spec.Containers[0].Env, proxyEnv(instance.Status.Proxy)...)
 	case res.CertManagerCainjectorName:
-		returningDeploy.Spec.Template.Spec.Containers[0].Image = imageRegistry + "/" + res.CainjectorImageName + ":" + res.ControllerImageVersion
+		component = instance.Spec.CainjectorConfig
+		image = componentImage(component.Image, imageRegistry, bundle.CainjectorImageName, bundle.ImageTag, postFix)
+		returningDeploy.Spec.Template.Spec.Containers[0].Args = bundle.CainjectorArgs(ns)
 	case res.CertManagerWebhookName:
-		returningDeploy.Spec.Template.Spec.Containers[0].Image = imageRegistry + "/" + res.WebhookImageName + ":" + res.WebhookImageVersion
-		returningDeploy.Spec.Template.Spec.Containers[0].SecurityContext.ReadOnlyRootFilesystem = &res.FalseVar
-		if instance.Spec.OCP311 {
-			returningDeploy.Spec.Template.Spec.HostNetwork = res.FalseVar
-		}
+		component = instance.Spec.WebhookConfig.ComponentSpec
+		image = componentImage(component.Image, imageRegistry, bundle.WebhookImageName, bundle.ImageTag, postFix)
+		res.SetWebhookHostNetwork(&returningDeploy, webhookHostNetwork(instance.Spec, instance.Status.Platform))
 	case res.ConfigmapWatcherName:
-		returningDeploy.Spec.Template.Spec.Containers[0].Image = imageRegistry + "/" + res.ConfigmapWatcherImageName + ":" + res.ConfigmapWatcherVersion
+		component = instance.Spec.ConfigmapWatcherConfig
+		image = componentImage(component.Image, watcherRegistry, res.ConfigmapWatcherImageName, res.ConfigmapWatcherVersion, postFix)
 	}
 
-	if instance.Spec.ImagePostFix != "" {
-		returningDeploy.Spec.Template.Spec.Containers[0].Image += instance.Spec.ImagePostFix
+	container := &returningDeploy.Spec.Template.Spec.Containers[0]
+	var argConflicts, envConflicts []string
+	container.Args, argConflicts = mergeArgs(container.Args, component.ExtraArgs)
+	container.Env, envConflicts = mergeEnv(container.Env, component.Env)
+	if len(argConflicts) > 0 || len(envConflicts) > 0 {
+		log.Info("Ignoring extra args and env that conflict with the ones the operator sets",
+			"deployment", deploy.Name, "args", argConflicts, "env", envConflicts)
 	}
 
+	returningDeploy.Spec.Template.Spec.Containers[0].Image = mirrorImage(image, instance.Spec.ImageMirrors)
+	if component.ImagePullPolicy != "" {
+		returningDeploy.Spec.Template.Spec.Containers[0].ImagePullPolicy = component.ImagePullPolicy
+	}
+	if len(instance.Spec.ImagePullSecrets) > 0 {
+		returningDeploy.Spec.Template.Spec.ImagePullSecrets = instance.Spec.ImagePullSecrets
+	}
+
+	returningDeploy.Spec.Template.Spec.Containers[0].Resources = componentResources(component.Resources)
+	probeTimings(returningDeploy.Spec.Template.Spec.Containers[0].LivenessProbe, component.LivenessProbe)
+	probeTimings(returningDeploy.Spec.Template.Spec.Containers[0].ReadinessProbe, component.ReadinessProbe)
+
+	placement := podPlacement(instance.Spec.PodPlacement, component.PodPlacement)
+	returningDeploy.Spec.Template.Spec.NodeSelector = placement.NodeSelector
+	returningDeploy.Spec.Template.Spec.Tolerations = placement.Tolerations
+	returningDeploy.Spec.Template.Spec.Affinity = placement.Affinity
+	returningDeploy.Spec.Template.Spec.TopologySpreadConstraints = placement.TopologySpreadConstraints
+	returningDeploy.Spec.Template.Spec.PriorityClassName = placement.PriorityClassName
+	res.SetReplicas(&returningDeploy, componentReplicas(deploy.Name, instance.Spec.HighAvailability, component.Replicas))
+
 	returningDeploy.Namespace = ns
 	log.V(2).Info("Resulting image registry", "full name", returningDeploy.Spec.Template.Spec.Containers[0].Image)
 	log.V(3).Info("Resulting deployment to be created", "spec", fmt.Sprintf("%v", returningDeploy))
 	return returningDeploy
 }
 
+// Flags the operator sets that the component's extra args may replace
+var overridableFlags = map[string]bool{"v": true}
+
+// Returns the name of the flag an arg sets
+func flagName(arg string) string {
+	return strings.TrimLeft(strings.SplitN(arg, "=", 2)[0], "-")
+}
+
+// Appends the extra args to the args the operator sets. An extra arg replaces the operator's arg for
+// the same flag if it is overridable, and is otherwise dropped and returned as a conflict.
+func mergeArgs(managed, extra []string) ([]string, []string) {
+	if len(extra) == 0 {
+		return managed, nil
+	}
+	managedFlags := make(map[string]bool, len(managed))
+	for _, arg := range managed {
+		managedFlags[flagName(arg)] = true
+	}
+	overridden := make(map[string]bool)
+	var appended, conflicts []string
+	for _, arg := range extra {
+		name := flagName(arg)
+		switch {
+		case !managedFlags[name]:
+			appended = append(appended, arg)
+		case overridableFlags[name]:
+			overridden[name] = true
+			appended = append(appended, arg)
+		default:
+			conflicts = append(conflicts, arg)
+		}
+	}
+	var args []string
+	for _, arg := range managed {
+		if !overridden[flagName(arg)] {
+			args = append(args, arg)
+		}
+	}
+	return append(args, appended...), conflicts
+}
+
+// Appends the extra env to the env the operator sets. Variables the operator
+// sets are dropped from the extra env and their names returned as conflicts.
+func mergeEnv(managed, extra []corev1.EnvVar) ([]corev1.EnvVar, []string) {
+	if len(extra) == 0 {
+		return managed, nil
+	}
+	managedNames := make(map[string]bool, len(managed))
+	for _, env := range managed {
+		managedNames[env.Name] = true
+	}
+	env := append([]corev1.EnvVar{}, managed...)
+	var conflicts []string
+	for _, extraEnv := range extra {
+		if managedNames[extraEnv.Name] {
+			conflicts = append(conflicts, extraEnv.Name)
+			continue
+		}
+		env = append(env, *extraEnv.DeepCopy())
+	}
+	return env, conflicts
+}
+
+// Returns the override if it is set. It is a full image reference, by tag or by digest, so nothing
+// is added to it. Otherwise returns the image with the tag and postfix in the registry.
+func componentImage(override, registry, name, tag, postFix string) string {
+	if override != "" {
+		return override
+	}
+	return registry + "/" + name + ":" + tag + postFix
+}
+
+// Returns the image pulled from the mirror of the longest source it starts with. A source only
+// matches up to a separator, so quay.io/opencloudio doesn't match quay.io/opencloudio-dev/image.
+func mirrorImage(image string, mirrors []operatorv1alpha1.ImageMirror) string {
+	var source, mirror string
+	for _, m := range mirrors {
+		prefix := strings.TrimRight(m.Source, "/")
+		if len(prefix) <= len(source) || !strings.HasPrefix(image, prefix) {
+			continue
+		}
+		if rest := image[len(prefix):]; rest == "" || strings.ContainsAny(rest[:1], "/:@") {
+			source, mirror = prefix, strings.TrimRight(m.Mirror, "/")
+		}
+	}
+	if source == "" {
+		return image
+	}
+	mirrored := mirror + strings.TrimPrefix(image, source)
+	log.V(2).Info("Pulling image from mirror", "image", image, "mirror", mirrored)
+	return mirrored
+}
+
+// Returns the number of replicas of the component. The configmap-watcher has no leader
+// election, so more than one replica would act on every change twice.
+func componentReplicas(name string, highAvailability bool, override *int32) int32 {
+	switch {
+	case name == res.ConfigmapWatcherName:
+		return 1
+	case override != nil:
+		return *override
+	case highAvailability:
+		return 2
+	}
+	return 1
+}
+
+// Returns the default container resources with the limits and requests
+// specified in the override replacing their default values
+func componentResources(override *corev1.ResourceRequirements) corev1.ResourceRequirements {
+	resources := res.DefaultResources()
+	if override == nil {
+		return resources
+	}
+	for name, quantity := range override.Limits {
+		resources.Limits[name] = quantity
+	}
+	for name, quantity := range override.Requests {
+		resources.Requests[name] = quantity
+		// A request above a default limit raises the limit, the API server rejects a request above its limit
+		if _, ok := override.Limits[name]; !ok {
+			if limit, ok := resources.Limits[name]; ok && quantity.Cmp(limit) > 0 {
+				resources.Limits[name] = quantity
+			}
+		}
+	}
+	return resources
+}
+
+// Replaces the timings of the probe with the ones that are set in the override.
+// Components without the probe are left without it.
+func probeTimings(probe *corev1.Probe, override *operatorv1alpha1.ProbeTimings) {
+	if probe == nil || override == nil {
+		return
+	}
+	if override.InitialDelaySeconds != nil {
+		probe.InitialDelaySeconds = *override.InitialDelaySeconds
+	}
+	if override.PeriodSeconds != nil {
+		probe.PeriodSeconds = *override.PeriodSeconds
+	}
+	if override.TimeoutSeconds != nil {
+		probe.TimeoutSeconds = *override.TimeoutSeconds
+	}
+	if override.FailureThreshold != nil {
+		probe.FailureThreshold = *override.FailureThreshold
+	}
+}
+
+// Returns the global pod placement with every field that is set on the
+// component's placement replacing the global value
+func podPlacement(global, component operatorv1alpha1.PodPlacement) operatorv1alpha1.PodPlacement {
+	placement := *global.DeepCopy()
+	if component.NodeSelector != nil {
+		placement.NodeSelector = component.NodeSelector
+	}
+	if component.Tolerations != nil {
+		placement.Tolerations = component.Tolerations
+	}
+	if component.Affinity != nil {
+		placement.Affinity = component.Affinity
+	}
+	if component.TopologySpreadConstraints != nil {
+		placement.TopologySpreadConstraints = component.TopologySpreadConstraints
+	}
+	if component.PriorityClassName != "" {
+		placement.PriorityClassName = component.PriorityClassName
+	}
+	return placement
+}
+
 func removeDeploy(client kubernetes.Interface, name, namespace string) error {
 	if err := client.AppsV1().Deployments(namespace).Delete(name, &metav1.DeleteOptions{}); err != nil {
 		log.V(1).Info("Error removing deployment", "name", name, "namespace", namespace, "error message", err)
@@ -211,7 +416,7 @@ func deployFinder(client kubernetes.Interface, labels, name string) []appsv1.Dep
 
 // Deep comparison between the two deployments passed in
 // Checks labels, replicas, pod template labels, pull secrets, service account names,
-// volumes, liveness, readiness, image name, args, env, and security contexts (pod & container)
+// volumes, scheduling constraints, liveness, readiness, image name, args, env, and security contexts (pod & container)
 // of both deployments. If there are any discrepencies between them, this returns false. Returns
 // true otherwise
 func equalDeploys(first, second appsv1.Deployment) bool {
@@ -289,6 +494,56 @@ func equalDeploys(first, second appsv1.Deployment) bool {
 		return false
 	}
 
+	// The DNS policy is defaulted to ClusterFirst by the API server
+	if dnsPolicy(firstPodTemplate.Spec.DNSPolicy) != dnsPolicy(secondPodTemplate.Spec.DNSPolicy) {
+		statusLog.Info("DNS policies not equal",
+			"first", firstPodTemplate.Spec.DNSPolicy,
+			"second", secondPodTemplate.Spec.DNSPolicy)
+		return false
+	}
+
+	if firstPodTemplate.Annotations["openshift.io/scc"] != secondPodTemplate.Annotations["openshift.io/scc"] {
+		statusLog.Info("Security context constraints not equal",
+			"first", firstPodTemplate.Annotations["openshift.io/scc"],
+			"second", secondPodTemplate.Annotations["openshift.io/scc"])
+		return false
+	}
+
+	if !equality.Semantic.DeepEqual(firstPodTemplate.Spec.NodeSelector, secondPodTemplate.Spec.NodeSelector) {
+		statusLog.Info("Node selectors not equal",
+			"first", fmt.Sprintf("%v", firstPodTemplate.Spec.NodeSelector),
+			"second", fmt.Sprintf("%v", secondPodTemplate.Spec.NodeSelector))
+		return false
+	}
+
+	if !equality.Semantic.DeepEqual(firstPodTemplate.Spec.Tolerations, secondPodTemplate.Spec.Tolerations) {
+		statusLog.Info("Tolerations not equal",
+			"first", fmt.Sprintf("%v", firstPodTemplate.Spec.Tolerations),
+			"second", fmt.Sprintf("%v", secondPodTemplate.Spec.Tolerations))
+		return false
+	}
+
+	if !equality.Semantic.DeepEqual(firstPodTemplate.Spec.Affinity, secondPodTemplate.Spec.Affinity) {
+		statusLog.Info("Affinities not equal",
+			"first", fmt.Sprintf("%v", firstPodTemplate.Spec.Affinity),
+			"second", fmt.Sprintf("%v", secondPodTemplate.Spec.Affinity))
+		return false
+	}
+
+	if !equality.Semantic.DeepEqual(firstPodTemplate.Spec.TopologySpreadConstraints, secondPodTemplate.Spec.TopologySpreadConstraints) {
+		statusLog.Info("Topology spread constraints not equal",
+			"first", fmt.Sprintf("%v", firstPodTemplate.Spec.TopologySpreadConstraints),
+			"second", fmt.Sprintf("%v", secondPodTemplate.Spec.TopologySpreadConstraints))
+		return false
+	}
+
+	if firstPodTemplate.Spec.PriorityClassName != secondPodTemplate.Spec.PriorityClassName {
+		statusLog.Info("Priority class names not equal",
+			"first", firstPodTemplate.Spec.PriorityClassName,
+			"second", secondPodTemplate.Spec.PriorityClassName)
+		return false
+	}
+
 	// Container level checks
 	firstContainers := firstPodTemplate.Spec.Containers
 	secondContainers := secondPodTemplate.Spec.Containers
@@ -333,56 +588,15 @@ func equalDeploys(first, second appsv1.Deployment) bool {
 		return false
 	}
 
-	fLive := fContainer.LivenessProbe
-	sLive := sContainer.LivenessProbe
-
-	if fLive != nil && sLive != nil {
-		if !reflect.DeepEqual(fLive.Handler.Exec.Command, sLive.Handler.Exec.Command) {
-			statusLog.Info("Exec command in liveness probes not equal",
-				"first", fLive.Handler.Exec.Command, "second", sLive.Handler.Exec.Command)
-			return false
-		}
-
-		if !reflect.DeepEqual(fLive.InitialDelaySeconds, sLive.InitialDelaySeconds) {
-			statusLog.Info("Initial delay seconds in liveness probes not equal",
-				"first", fLive.InitialDelaySeconds, "second", sLive.InitialDelaySeconds)
-			return false
-		}
-
-		if !reflect.DeepEqual(fLive.TimeoutSeconds, sLive.TimeoutSeconds) {
-			statusLog.Info("Timeout seconds in liveness probes not equal",
-				"first", fLive.TimeoutSeconds, "second", sLive.TimeoutSeconds)
-			return false
-		}
-	} else if !(fLive == nil && sLive == nil) {
-		statusLog.Info("One liveness probe is nil",
-			"first", fmt.Sprintf("%v", fLive), "second", fmt.Sprintf("%v", sLive))
+	if !equalProbes(fContainer.LivenessProbe, sContainer.LivenessProbe) {
+		statusLog.Info("Liveness probes not equal",
+			"first", fmt.Sprintf("%v", fContainer.LivenessProbe), "second", fmt.Sprintf("%v", sContainer.LivenessProbe))
 		return false
 	}
 
-	fReady := fContainer.ReadinessProbe
-	sReady := sContainer.ReadinessProbe
-	if fReady != nil && sReady != nil {
-		if !reflect.DeepEqual(fReady.Handler.Exec.Command, sReady.Handler.Exec.Command) {
-			statusLog.Info("Exec command in readiness probes not equal",
-				"first", fReady.Handler.Exec.Command, "second", sReady.Handler.Exec.Command)
-			return false
-		}
-
-		if !reflect.DeepEqual(fReady.InitialDelaySeconds, sReady.InitialDelaySeconds) {
-			statusLog.Info("Initial delay seconds in readiness probes not equal",
-				"first", fReady.InitialDelaySeconds, "second", sReady.InitialDelaySeconds)
-			return false
-		}
-
-		if !reflect.DeepEqual(fReady.TimeoutSeconds, sReady.TimeoutSeconds) {
-			statusLog.Info("Timeout seconds in readiness probes not equal",
-				"first", fReady.TimeoutSeconds, "second", sReady.TimeoutSeconds)
-			return false
-		}
-	} else if !(fReady == nil && sReady == nil) {
-		statusLog.Info("One of the readiness probes is nil",
-			"first", fmt.Sprintf("%v", fReady), "second", fmt.Sprintf("%v", sReady))
+	if !equalProbes(fContainer.ReadinessProbe, sContainer.ReadinessProbe) {
+		statusLog.Info("Readiness probes not equal",
+			"first", fmt.Sprintf("%v", fContainer.ReadinessProbe), "second", fmt.Sprintf("%v", sContainer.ReadinessProbe))
 		return false
 	}
 
@@ -463,27 +677,15 @@ func equalDeploys(first, second appsv1.Deployment) bool {
 	fRes := fContainer.Resources
 	sRes := sContainer.Resources
 
-	if !reflect.DeepEqual(fRes.Limits[corev1.ResourceCPU], sRes.Limits[corev1.ResourceCPU]) {
-		statusLog.Info("Resource limit cpu not equal",
-			"first", fRes.Limits[corev1.ResourceCPU], "second", sRes.Limits[corev1.ResourceCPU])
+	if !equalResourceLists(fRes.Limits, sRes.Limits) {
+		statusLog.Info("Resource limits not equal",
+			"first", fmt.Sprintf("%v", fRes.Limits), "second", fmt.Sprintf("%v", sRes.Limits))
 		return false
 	}
 
-	if !reflect.DeepEqual(fRes.Limits[corev1.ResourceMemory], sRes.Limits[corev1.ResourceMemory]) {
-		statusLog.Info("Resource limit memory not equal",
-			"first", fRes.Limits[corev1.ResourceMemory], "second", sRes.Limits[corev1.ResourceMemory])
-		return false
-	}
-
-	if !reflect.DeepEqual(fRes.Requests[corev1.ResourceCPU], sRes.Requests[corev1.ResourceCPU]) {
-		statusLog.Info("Resource requests cpu not equal",
-			"first", fRes.Requests[corev1.ResourceCPU], "second", sRes.Requests[corev1.ResourceCPU])
-		return false
-	}
-
-	if !reflect.DeepEqual(fRes.Requests[corev1.ResourceMemory], sRes.Requests[corev1.ResourceMemory]) {
-		statusLog.Info("Resource requests memory not equal",
-			"first", fRes.Requests[corev1.ResourceMemory], "second", sRes.Requests[corev1.ResourceMemory])
+	if !equalResourceLists(fRes.Requests, sRes.Requests) {
+		statusLog.Info("Resource requests not equal",
+			"first", fmt.Sprintf("%v", fRes.Requests), "second", fmt.Sprintf("%v", sRes.Requests))
 		return false
 	}
 
@@ -518,6 +720,14 @@ func equalDeploys(first, second appsv1.Deployment) bool {
 					statusLog.Info("One of the env's field ref is nil")
 					return false
 				}
+				// The other sources, which the component's env can use, have no defaults
+				fSource, sSource := *fEnv[i].ValueFrom, *sEnv[i].ValueFrom
+				fSource.FieldRef, sSource.FieldRef = nil, nil
+				if !equality.Semantic.DeepEqual(fSource, sSource) {
+					statusLog.Info("Env sources not equal",
+						"first", fmt.Sprintf("%v", fSource), "second", fmt.Sprintf("%v", sSource))
+					return false
+				}
 
 			} else if !(fEnv[i].ValueFrom == nil && sEnv[i].ValueFrom == nil) {
 				statusLog.Info("Container number", "first", i)
@@ -547,6 +757,21 @@ func equalDeploys(first, second appsv1.Deployment) bool {
 	return true
 }
 
+// Compares the quantities of both resource lists by value rather than by
+// their representation so that 0.5 and 500m are treated as equal
+func equalResourceLists(first, second corev1.ResourceList) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for name, quantity := range first {
+		other, ok := second[name]
+		if !ok || quantity.Cmp(other) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 func isSubset(first, second map[string]string) bool {
 	for k, v := range first {
 		val, ok := second[k]
@@ -562,3 +787,39 @@ func isSubset(first, second map[string]string) bool {
 	}
 	return true
 }
+
+func dnsPolicy(policy corev1.DNSPolicy) corev1.DNSPolicy {
+	if policy == "" {
+		return corev1.DNSClusterFirst
+	}
+	return policy
+}
+
+// Returns true if the probes run the same check with the same timings. The fields
+// the API server defaults are compared with their default values filled in.
+func equalProbes(first, second *corev1.Probe) bool {
+	if first == nil || second == nil {
+		return first == nil && second == nil
+	}
+	return equality.Semantic.DeepEqual(probeDefaults(first), probeDefaults(second))
+}
+
+func probeDefaults(probe *corev1.Probe) *corev1.Probe {
+	defaulted := probe.DeepCopy()
+	if defaulted.TimeoutSeconds == 0 {
+		defaulted.TimeoutSeconds = 1
+	}
+	if defaulted.PeriodSeconds == 0 {
+		defaulted.PeriodSeconds = 10
+	}
+	if defaulted.SuccessThreshold == 0 {
+		defaulted.SuccessThreshold = 1
+	}
+	if defaulted.FailureThreshold == 0 {
+		defaulted.FailureThreshold = 3
+	}
+	if defaulted.HTTPGet != nil && defaulted.HTTPGet.Scheme == "" {
+		defaulted.HTTPGet.Scheme = corev1.URISchemeHTTP
+	}
+	return defaulted
+}
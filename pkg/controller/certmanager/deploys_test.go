@@ -0,0 +1,71 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestComponentResources(t *testing.T) {
+	tests := []struct {
+		name                               string
+		override                           *corev1.ResourceRequirements
+		wantMemoryRequest, wantMemoryLimit string
+	}{
+		{
+			name:              "defaults",
+			wantMemoryRequest: "300Mi",
+			wantMemoryLimit:   "500Mi",
+		},
+		{
+			name:              "request below the default limit",
+			override:          &corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("400Mi")}},
+			wantMemoryRequest: "400Mi",
+			wantMemoryLimit:   "500Mi",
+		},
+		{
+			name:              "request above the default limit raises it",
+			override:          &corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")}},
+			wantMemoryRequest: "1Gi",
+			wantMemoryLimit:   "1Gi",
+		},
+		{
+			name: "limit set with the request is kept",
+			override: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+				Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+			},
+			wantMemoryRequest: "1Gi",
+			wantMemoryLimit:   "2Gi",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources := componentResources(tt.override)
+			request, limit := resources.Requests[corev1.ResourceMemory], resources.Limits[corev1.ResourceMemory]
+			if request.Cmp(resource.MustParse(tt.wantMemoryRequest)) != 0 {
+				t.Errorf("memory request = %s, want %s", request.String(), tt.wantMemoryRequest)
+			}
+			if limit.Cmp(resource.MustParse(tt.wantMemoryLimit)) != 0 {
+				t.Errorf("memory limit = %s, want %s", limit.String(), tt.wantMemoryLimit)
+			}
+		})
+	}
+}
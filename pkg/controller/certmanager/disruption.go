@@ -0,0 +1,78 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"context"
+
+	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
+	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
+
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Keeps one of the deployment's pods running through voluntary disruptions, such as node drains,
+// when it runs more than one replica. A single replica gets no budget, so that it doesn't block drains.
+func disruptionBudget(instance *operatorv1alpha1.CertManager, client client.Client, scheme *runtime.Scheme, deploy *appsv1.Deployment) error {
+	if deploy.Spec.Replicas == nil || *deploy.Spec.Replicas < 2 {
+		return removeDisruptionBudget(client, deploy.Name, deploy.Namespace)
+	}
+
+	desired := res.PodDisruptionBudget(deploy)
+	if err := controllerutil.SetControllerReference(instance, desired, scheme); err != nil {
+		log.Error(err, "Error setting controller reference on pod disruption budget")
+	}
+	existing := &policyv1beta1.PodDisruptionBudget{}
+	err := client.Get(context.Background(), types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, existing)
+	if err != nil && apiErrors.IsNotFound(err) {
+		return client.Create(context.Background(), desired)
+	} else if err != nil {
+		return err
+	}
+
+	if equality.Semantic.DeepEqual(existing.Spec, desired.Spec) && isSubset(desired.Labels, existing.Labels) {
+		return nil
+	}
+	// The spec of a pod disruption budget can't be updated before Kubernetes 1.15, so it is recreated
+	log.V(1).Info("Pod disruption budget has drifted, recreating it", "name", existing.Name)
+	if err := client.Delete(context.Background(), existing); err != nil && !apiErrors.IsNotFound(err) {
+		return err
+	}
+	return client.Create(context.Background(), desired)
+}
+
+func removeDisruptionBudget(client client.Client, name, ns string) error {
+	pdb := &policyv1beta1.PodDisruptionBudget{}
+	err := client.Get(context.Background(), types.NamespacedName{Name: name, Namespace: ns}, pdb)
+	if err != nil {
+		if !apiErrors.IsNotFound(err) {
+			return err
+		}
+	} else {
+		if err := client.Delete(context.Background(), pdb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
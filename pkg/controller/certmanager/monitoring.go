@@ -0,0 +1,188 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"context"
+
+	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
+	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
+
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Exposes cert-manager-controller's metrics with a service, a ServiceMonitor and a PrometheusRule when
+// monitoring is enabled and the cluster serves the Prometheus operator's API, and removes them otherwise
+func (r *ReconcileCertManager) monitoring(instance *operatorv1alpha1.CertManager, bundle *res.Bundle) error {
+	served := servesGroupVersion(r.kubeclient.Discovery(), monitoringv1.SchemeGroupVersion.String())
+	if !instance.Spec.Metrics.Monitoring || !served {
+		if instance.Spec.Metrics.Monitoring {
+			r.updateEvent(instance, "Monitoring is enabled but the cluster doesn't serve "+monitoringv1.SchemeGroupVersion.String(),
+				corev1.EventTypeWarning, "MonitoringUnavailable")
+		}
+		return r.removeMonitoring(served)
+	}
+
+	if err := r.metricsService(instance); err != nil {
+		return err
+	}
+	if err := r.serviceMonitor(instance); err != nil {
+		return err
+	}
+	return r.prometheusRule(instance, bundle)
+}
+
+func (r *ReconcileCertManager) metricsService(instance *operatorv1alpha1.CertManager) error {
+	desired := res.ControllerMetricsSvc(r.ns)
+	if err := controllerutil.SetControllerReference(instance, desired, r.scheme); err != nil {
+		log.Error(err, "Error setting controller reference on metrics service")
+	}
+	svc := &corev1.Service{}
+	err := r.client.Get(context.Background(), types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, svc)
+	if err != nil && apiErrors.IsNotFound(err) {
+		return r.client.Create(context.Background(), desired)
+	} else if err != nil {
+		return err
+	}
+	if !equality.Semantic.DeepEqual(svc.Spec.Ports, desired.Spec.Ports) ||
+		!equality.Semantic.DeepEqual(svc.Spec.Selector, desired.Spec.Selector) || !isSubset(desired.Labels, svc.Labels) {
+		log.V(1).Info("Metrics service has drifted, updating it", "name", svc.Name)
+		svc.Spec.Ports = desired.Spec.Ports
+		svc.Spec.Selector = desired.Spec.Selector
+		svc.Labels = mergeMaps(svc.Labels, desired.Labels)
+		return r.client.Update(context.Background(), svc)
+	}
+	return nil
+}
+
+func (r *ReconcileCertManager) serviceMonitor(instance *operatorv1alpha1.CertManager) error {
+	desired := res.ControllerServiceMonitor(r.ns)
+	if err := controllerutil.SetControllerReference(instance, desired, r.scheme); err != nil {
+		log.Error(err, "Error setting controller reference on service monitor")
+	}
+	client := r.monclient.ServiceMonitors(r.ns)
+	existing, err := client.Get(desired.Name, metav1.GetOptions{})
+	if err != nil && apiErrors.IsNotFound(err) {
+		_, err = client.Create(desired)
+		return err
+	} else if err != nil {
+		return err
+	}
+	if !equality.Semantic.DeepEqual(existing.Spec, desired.Spec) || !isSubset(desired.Labels, existing.Labels) {
+		log.V(1).Info("Service monitor has drifted, updating it", "name", existing.Name)
+		existing.Spec = desired.Spec
+		existing.Labels = mergeMaps(existing.Labels, desired.Labels)
+		_, err = client.Update(existing)
+		return err
+	}
+	return nil
+}
+
+func (r *ReconcileCertManager) prometheusRule(instance *operatorv1alpha1.CertManager, bundle *res.Bundle) error {
+	desired := res.PrometheusRule(bundle, r.ns, instance.Spec.Webhook, instance.Spec.Metrics.Certificates)
+	if err := controllerutil.SetControllerReference(instance, desired, r.scheme); err != nil {
+		log.Error(err, "Error setting controller reference on prometheus rule")
+	}
+	client := r.monclient.PrometheusRules(r.ns)
+	existing, err := client.Get(desired.Name, metav1.GetOptions{})
+	if err != nil && apiErrors.IsNotFound(err) {
+		_, err = client.Create(desired)
+		return err
+	} else if err != nil {
+		return err
+	}
+	if !equality.Semantic.DeepEqual(existing.Spec, desired.Spec) || !isSubset(desired.Labels, existing.Labels) {
+		log.V(1).Info("Prometheus rule has drifted, updating it", "name", existing.Name)
+		existing.Spec = desired.Spec
+		existing.Labels = mergeMaps(existing.Labels, desired.Labels)
+		_, err = client.Update(existing)
+		return err
+	}
+	return nil
+}
+
+// Removes the metrics service, and the ServiceMonitor and PrometheusRule when their API is served
+func (r *ReconcileCertManager) removeMonitoring(served bool) error {
+	svc := &corev1.Service{}
+	err := r.client.Get(context.Background(), types.NamespacedName{Name: res.ControllerMetricsName, Namespace: r.ns}, svc)
+	if err == nil {
+		if err := r.client.Delete(context.Background(), svc); err != nil && !apiErrors.IsNotFound(err) {
+			return err
+		}
+	} else if !apiErrors.IsNotFound(err) {
+		return err
+	}
+	if !served {
+		return nil
+	}
+	if err := r.monclient.ServiceMonitors(r.ns).Delete(res.ControllerMetricsName, &metav1.DeleteOptions{}); err != nil && !apiErrors.IsNotFound(err) {
+		return err
+	}
+	if err := r.monclient.PrometheusRules(r.ns).Delete(res.ControllerMetricsName, &metav1.DeleteOptions{}); err != nil && !apiErrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Removes the instance's owner reference from the ServiceMonitor and PrometheusRule, so that they
+// aren't garbage collected with it. The metrics service is orphaned along with the other owned resources.
+func (r *ReconcileCertManager) orphanMonitoring(instance *operatorv1alpha1.CertManager) error {
+	if !servesGroupVersion(r.kubeclient.Discovery(), monitoringv1.SchemeGroupVersion.String()) {
+		return nil
+	}
+	withoutOwner := func(refs []metav1.OwnerReference) ([]metav1.OwnerReference, bool) {
+		var kept []metav1.OwnerReference
+		for _, ref := range refs {
+			if ref.UID != instance.UID {
+				kept = append(kept, ref)
+			}
+		}
+		return kept, len(kept) != len(refs)
+	}
+
+	monitor, err := r.monclient.ServiceMonitors(r.ns).Get(res.ControllerMetricsName, metav1.GetOptions{})
+	if err != nil && !apiErrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		if refs, changed := withoutOwner(monitor.OwnerReferences); changed {
+			monitor.OwnerReferences = refs
+			if _, err := r.monclient.ServiceMonitors(r.ns).Update(monitor); err != nil {
+				return err
+			}
+		}
+	}
+	rule, err := r.monclient.PrometheusRules(r.ns).Get(res.ControllerMetricsName, metav1.GetOptions{})
+	if err != nil && !apiErrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		if refs, changed := withoutOwner(rule.OwnerReferences); changed {
+			rule.OwnerReferences = refs
+			if _, err := r.monclient.PrometheusRules(r.ns).Update(rule); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
+
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	admRegv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// How often the operator's webhook resources and serving certificate are checked
+const operatorWebhookResync = time.Hour
+
+// How often a failed reconcile of the operator's webhook is retried
+const operatorWebhookRetry = 30 * time.Second
+
+// AddWebhook serves the operator's admission webhook, which validates CertManagers and fills in their
+// defaults, and passes the proxy to the acmesolver pods. Its serving certificate is generated by the operator and renewed before it expires.
+func AddWebhook(mgr manager.Manager) error {
+	ns, _ := k8sutil.GetWatchNamespace()
+	if ns == "" {
+		ns = res.DeployNamespace
+	}
+	// The manager's cache isn't started until all components are added, so the client reads from the API server
+	c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		return err
+	}
+	kubeclient, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+
+	w := &operatorWebhook{
+		client:    c,
+		admission: newAdmissionClient(c, kubeclient.Discovery()),
+		ns:        ns,
+		certDir:   filepath.Join(os.TempDir(), res.OperatorWebhookName, "serving-certs"),
+	}
+	// The server needs its certificate before it starts. When it can't be reconciled yet, a temporary
+	// certificate is served until the reconcile loop succeeds, rather than failing the operator.
+	if err := w.reconcile(); err != nil {
+		log.Error(err, "Error reconciling the operator's webhook, retrying")
+		if err := w.temporaryCert(); err != nil {
+			return err
+		}
+	}
+
+	server := mgr.GetWebhookServer()
+	server.Port = res.OperatorWebhookPort
+	server.CertDir = w.certDir
+	server.Register(res.OperatorMutatePath, &webhook.Admission{Handler: &certManagerDefaulter{decoder: decoder}})
+	server.Register(res.OperatorValidatePath, &webhook.Admission{Handler: &certManagerValidator{client: c, decoder: decoder, ns: ns}})
+	server.Register(res.OperatorSolverPodPath, &webhook.Admission{Handler: &acmesolverProxyInjector{client: c, decoder: decoder}})
+	return mgr.Add(w)
+}
+
+// operatorWebhook keeps the operator's webhook configurations, service and serving certificate up to date
+type operatorWebhook struct {
+	client    client.Client
+	admission *admissionClient
+	ns        string
+	certDir   string
+}
+
+// Start periodically renews the serving certificate and reverts changes to the webhook's resources.
+// A failed reconcile is retried until it succeeds.
+func (w *operatorWebhook) Start(stop <-chan struct{}) error {
+	wait.Until(func() {
+		_ = wait.PollImmediateUntil(operatorWebhookRetry, func() (bool, error) {
+			if err := w.reconcile(); err != nil {
+				log.Error(err, "Error reconciling the operator's webhook")
+				return false, nil
+			}
+			return true, nil
+		}, stop)
+	}, operatorWebhookResync, stop)
+	return nil
+}
+
+func (w *operatorWebhook) reconcile() error {
+	cert, err := w.servingCert()
+	if err != nil {
+		return err
+	}
+	if err := writeServingCert(w.certDir, cert); err != nil {
+		return err
+	}
+	if err := w.service(); err != nil {
+		return err
+	}
+	return w.configurations(cert.caCert)
+}
+
+// Writes a serving certificate that isn't kept in the operator's secret, unless one was already written
+func (w *operatorWebhook) temporaryCert() error {
+	if _, err := os.Stat(filepath.Join(w.certDir, corev1.TLSCertKey)); err == nil {
+		return nil
+	}
+	cert, _, err := renewServingCert(&servingCert{}, w.dnsNames(), time.Now())
+	if err != nil {
+		return err
+	}
+	return writeServingCert(w.certDir, cert)
+}
+
+// Returns the DNS names of the operator's webhook service
+func (w *operatorWebhook) dnsNames() []string {
+	svc := res.OperatorWebhookName
+	return []string{svc, svc + "." + w.ns, svc + "." + w.ns + ".svc", svc + "." + w.ns + ".svc.cluster.local"}
+}
+
+// Returns the serving certificate kept in the operator's secret, renewing it when needed
+func (w *operatorWebhook) servingCert() (*servingCert, error) {
+	secret := &corev1.Secret{}
+	err := w.client.Get(context.Background(), types.NamespacedName{Name: res.OperatorWebhookName, Namespace: w.ns}, secret)
+	if err != nil && !apiErrors.IsNotFound(err) {
+		return nil, err
+	}
+	create := apiErrors.IsNotFound(err)
+
+	cert, renewed, err := renewServingCert(servingCertFromSecret(secret), w.dnsNames(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if create {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: res.OperatorWebhookName, Namespace: w.ns},
+			Type:       corev1.SecretTypeTLS,
+			Data:       cert.secretData(),
+		}
+		if err := w.client.Create(context.Background(), secret); err != nil {
+			return nil, err
+		}
+	} else if renewed {
+		secret.Data = cert.secretData()
+		if err := w.client.Update(context.Background(), secret); err != nil {
+			return nil, err
+		}
+	}
+	return cert, nil
+}
+
+func (w *operatorWebhook) service() error {
+	desired := res.OperatorWebhookSvc(w.ns)
+	svc := &corev1.Service{}
+	err := w.client.Get(context.Background(), types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, svc)
+	if err != nil && apiErrors.IsNotFound(err) {
+		return w.client.Create(context.Background(), desired)
+	} else if err != nil {
+		return err
+	}
+	if !equality.Semantic.DeepEqual(svc.Spec.Ports, desired.Spec.Ports) ||
+		!equality.Semantic.DeepEqual(svc.Spec.Selector, desired.Spec.Selector) {
+		log.V(1).Info("Operator webhook service has drifted, updating it", "name", svc.Name)
+		svc.Spec.Ports = desired.Spec.Ports
+		svc.Spec.Selector = desired.Spec.Selector
+		return w.client.Update(context.Background(), svc)
+	}
+	return nil
+}
+
+// Creates or updates the webhook configurations that send CertManagers to the operator
+func (w *operatorWebhook) configurations(caBundle []byte) error {
+	desiredMutating := res.OperatorMutatingWebhook(w.ns, caBundle)
+	mutating := &admRegv1beta1.MutatingWebhookConfiguration{}
+	err := w.admission.get(types.NamespacedName{Name: desiredMutating.Name}, mutating)
+	if err != nil && apiErrors.IsNotFound(err) {
+		if err := w.admission.create(desiredMutating); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	} else {
+		desired := mutatingWebhookDefaults(desiredMutating.Webhooks, mutating.Webhooks)
+		for i := range desired {
+			desired[i].ClientConfig.CABundle = caBundle
+		}
+		if !equality.Semantic.DeepEqual(mutating.Webhooks, desired) || !isSubset(desiredMutating.Labels, mutating.Labels) {
+			log.V(1).Info("Operator mutating webhook configuration has drifted, updating it", "name", mutating.Name)
+			mutating.Webhooks = desired
+			mutating.Labels = mergeMaps(mutating.Labels, desiredMutating.Labels)
+			if err := w.admission.update(mutating); err != nil {
+				return err
+			}
+		}
+	}
+
+	desiredValidating := res.OperatorValidatingWebhook(w.ns, caBundle)
+	validating := &admRegv1beta1.ValidatingWebhookConfiguration{}
+	err = w.admission.get(types.NamespacedName{Name: desiredValidating.Name}, validating)
+	if err != nil && apiErrors.IsNotFound(err) {
+		return w.admission.create(desiredValidating)
+	} else if err != nil {
+		return err
+	}
+	desired := validatingWebhookDefaults(desiredValidating.Webhooks, validating.Webhooks)
+	for i := range desired {
+		desired[i].ClientConfig.CABundle = caBundle
+	}
+	if !equality.Semantic.DeepEqual(validating.Webhooks, desired) || !isSubset(desiredValidating.Labels, validating.Labels) {
+		log.V(1).Info("Operator validating webhook configuration has drifted, updating it", "name", validating.Name)
+		validating.Webhooks = desired
+		validating.Labels = mergeMaps(validating.Labels, desiredValidating.Labels)
+		return w.admission.update(validating)
+	}
+	return nil
+}
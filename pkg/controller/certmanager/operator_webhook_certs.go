@@ -0,0 +1,184 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	caCertKey = "ca.crt"
+	caKeyKey  = "ca.key"
+
+	// The CA is renewed a year before it expires, the serving certificate a month before
+	caValidity          = 10 * 365 * 24 * time.Hour
+	caRenewBefore       = 365 * 24 * time.Hour
+	servingValidity     = 365 * 24 * time.Hour
+	servingRenewBefore  = 30 * 24 * time.Hour
+	servingCertKeyBits  = 2048
+	servingCertFileMode = 0600
+)
+
+// servingCert is a serving certificate and the CA that signed it, PEM encoded
+type servingCert struct {
+	caCert, caKey, cert, key []byte
+}
+
+// Reads the certificate from the data of a secret
+func servingCertFromSecret(secret *corev1.Secret) *servingCert {
+	return &servingCert{
+		caCert: secret.Data[caCertKey],
+		caKey:  secret.Data[caKeyKey],
+		cert:   secret.Data[corev1.TLSCertKey],
+		key:    secret.Data[corev1.TLSPrivateKeyKey],
+	}
+}
+
+// Returns the data of a kubernetes.io/tls secret holding the certificate and its CA
+func (s *servingCert) secretData() map[string][]byte {
+	return map[string][]byte{
+		caCertKey:               s.caCert,
+		caKeyKey:                s.caKey,
+		corev1.TLSCertKey:       s.cert,
+		corev1.TLSPrivateKeyKey: s.key,
+	}
+}
+
+// Returns a certificate that is valid for the DNS names for at least the renewal period, renewing
+// the serving certificate or the CA as needed. The second return value is true if anything was renewed.
+func renewServingCert(current *servingCert, dnsNames []string, now time.Time) (*servingCert, bool, error) {
+	renewed := false
+	caCert, caKey, err := parseCertAndKey(current.caCert, current.caKey)
+	if err != nil || now.Add(caRenewBefore).After(caCert.NotAfter) {
+		log.Info("Generating a new CA for the operator's webhook")
+		current = &servingCert{}
+		if current.caCert, current.caKey, err = generateCert(nil, nil, nil, caValidity, now); err != nil {
+			return nil, false, err
+		}
+		if caCert, caKey, err = parseCertAndKey(current.caCert, current.caKey); err != nil {
+			return nil, false, err
+		}
+		renewed = true
+	}
+
+	cert, _, err := parseCertAndKey(current.cert, current.key)
+	if err != nil || now.Add(servingRenewBefore).After(cert.NotAfter) ||
+		cert.CheckSignatureFrom(caCert) != nil || !reflect.DeepEqual(cert.DNSNames, dnsNames) {
+		log.Info("Generating a new serving certificate for the operator's webhook")
+		next := &servingCert{caCert: current.caCert, caKey: current.caKey}
+		if next.cert, next.key, err = generateCert(caCert, caKey, dnsNames, servingValidity, now); err != nil {
+			return nil, false, err
+		}
+		current = next
+		renewed = true
+	}
+	return current, renewed, nil
+}
+
+// Generates a certificate and key signed by the CA, or a self-signed CA if none is given
+func generateCert(ca *x509.Certificate, caKey *rsa.PrivateKey, dnsNames []string, validity time.Duration, now time.Time) ([]byte, []byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, servingCertKeyBits)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+	}
+	parent, signer := template, key
+	if ca == nil {
+		template.Subject = pkix.Name{CommonName: "ibm-cert-manager-operator-webhook-ca"}
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	} else {
+		template.Subject = pkix.Name{CommonName: dnsNames[0]}
+		template.DNSNames = dnsNames
+		template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		parent, signer = ca, caKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return certPEM, keyPEM, nil
+}
+
+// Parses a PEM encoded certificate and its RSA key
+func parseCertAndKey(certPEM, keyPEM []byte) (*x509.Certificate, *rsa.PrivateKey, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, errors.New("Error decoding the certificate or key")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// Writes the serving certificate and key into the directory the webhook server reads them from.
+// The server watches the files, so they are only written when they changed.
+func writeServingCert(dir string, cert *servingCert) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{corev1.TLSCertKey, cert.cert},
+		{corev1.TLSPrivateKeyKey, cert.key},
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, file.name)
+		if existing, err := ioutil.ReadFile(path); err == nil && bytes.Equal(existing, file.data) {
+			continue
+		}
+		if err := ioutil.WriteFile(path, file.data, servingCertFileMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestRenewServingCert(t *testing.T) {
+	dnsNames := []string{"ibm-cert-manager-operator-webhook", "ibm-cert-manager-operator-webhook.ibm-common-services"}
+	issued := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	current, renewed, err := renewServingCert(&servingCert{}, dnsNames, issued)
+	if err != nil {
+		t.Fatalf("renewServingCert() error = %v", err)
+	}
+	if !renewed {
+		t.Fatal("renewServingCert() didn't generate a certificate")
+	}
+
+	tests := []struct {
+		name                string
+		dnsNames            []string
+		now                 time.Time
+		wantCA, wantServing bool
+	}{
+		{
+			name:     "valid",
+			dnsNames: dnsNames,
+			now:      issued,
+		},
+		{
+			name:     "serving certificate before its renewal window",
+			dnsNames: dnsNames,
+			now:      issued.Add(servingValidity - servingRenewBefore - time.Hour),
+		},
+		{
+			name:        "serving certificate in its renewal window",
+			dnsNames:    dnsNames,
+			now:         issued.Add(servingValidity - servingRenewBefore + time.Hour),
+			wantServing: true,
+		},
+		{
+			name:        "serving certificate expired",
+			dnsNames:    dnsNames,
+			now:         issued.Add(servingValidity + time.Hour),
+			wantServing: true,
+		},
+		{
+			name:        "dns names changed",
+			dnsNames:    dnsNames[:1],
+			now:         issued,
+			wantServing: true,
+		},
+		{
+			name:        "ca before its renewal window",
+			dnsNames:    dnsNames,
+			now:         issued.Add(caValidity - caRenewBefore - time.Hour),
+			wantServing: true,
+		},
+		{
+			name:        "ca in its renewal window",
+			dnsNames:    dnsNames,
+			now:         issued.Add(caValidity - caRenewBefore + time.Hour),
+			wantCA:      true,
+			wantServing: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, renewed, err := renewServingCert(current, test.dnsNames, test.now)
+			if err != nil {
+				t.Fatalf("renewServingCert() error = %v", err)
+			}
+			caRenewed := !bytes.Equal(got.caCert, current.caCert)
+			servingRenewed := !bytes.Equal(got.cert, current.cert)
+			if caRenewed != test.wantCA || servingRenewed != test.wantServing || renewed != (test.wantCA || test.wantServing) {
+				t.Errorf("renewServingCert() renewed = %v, ca renewed = %v, serving certificate renewed = %v, want ca %v, serving certificate %v",
+					renewed, caRenewed, servingRenewed, test.wantCA, test.wantServing)
+			}
+			cert, _, err := parseCertAndKey(got.cert, got.key)
+			if err != nil {
+				t.Fatalf("parseCertAndKey() error = %v", err)
+			}
+			ca, _, err := parseCertAndKey(got.caCert, got.caKey)
+			if err != nil {
+				t.Fatalf("parseCertAndKey() error = %v", err)
+			}
+			if err := cert.CheckSignatureFrom(ca); err != nil {
+				t.Errorf("serving certificate isn't signed by the ca: %v", err)
+			}
+			if !test.now.Before(cert.NotAfter.Add(-servingRenewBefore)) || !test.now.Before(ca.NotAfter.Add(-caRenewBefore)) {
+				t.Errorf("certificate expires within its renewal window: serving certificate %v, ca %v", cert.NotAfter, ca.NotAfter)
+			}
+		})
+	}
+}
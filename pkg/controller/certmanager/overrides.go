@@ -0,0 +1,157 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
+	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+// overridable is an object overrides can patch, as the operator renders it for a CertManager
+type overridable struct {
+	kind   string
+	name   string
+	object runtime.Object
+}
+
+// Returns every object overrides can patch, including those that are only deployed with the webhook
+func overridableObjects(instance *operatorv1alpha1.CertManager, bundle *res.Bundle, ns string) []overridable {
+	var objects []overridable
+	for _, deploy := range []*appsv1.Deployment{bundle.ControllerDeployment(ns), bundle.CainjectorDeployment(ns),
+		bundle.WebhookDeployment(ns), res.ConfigmapWatcherDeployment(ns)} {
+		rendered := setupDeploy(instance, bundle, deploy, ns)
+		objects = append(objects, overridable{kind: "Deployment", name: deploy.Name, object: &rendered})
+	}
+	objects = append(objects,
+		overridable{kind: "ClusterRole", name: res.ClusterRoleName, object: bundle.ClusterRole()},
+		overridable{kind: "ClusterRoleBinding", name: res.ClusterRoleName, object: res.DefaultClusterRoleBinding(ns)},
+		overridable{kind: "RoleBinding", name: res.CertManagerWebhookName, object: res.WebhookRoleBinding(ns)},
+		overridable{kind: "Service", name: res.CertManagerWebhookName, object: bundle.WebhookSvc(ns)},
+		overridable{kind: "MutatingWebhookConfiguration", name: res.CertManagerWebhookName, object: mutatingWebhook(instance, bundle, ns)},
+		overridable{kind: "ValidatingWebhookConfiguration", name: res.CertManagerWebhookName, object: validatingWebhook(instance, bundle, ns)},
+	)
+	if bundle.APIService {
+		objects = append(objects, overridable{kind: "APIService", name: res.APISvcName, object: res.APIService(ns)})
+	}
+	return objects
+}
+
+// Patches the rendered object with the CertManager's overrides of its kind and name, in the order they are listed.
+// Overrides that can't be applied are skipped, they are reported by validateOverrides.
+func applyOverrides(instance *operatorv1alpha1.CertManager, kind, name string, obj runtime.Object) {
+	for i, override := range instance.Spec.Overrides {
+		if override.Kind != kind || override.Name != name {
+			continue
+		}
+		if err := applyOverride(override, obj); err != nil {
+			log.Info("Skipping override that can't be applied", "index", i, "kind", kind, "name", name, "error", err.Error())
+		}
+	}
+}
+
+// Patches the object with the override. The object is left as it was if the patch can't be applied.
+func applyOverride(override operatorv1alpha1.Override, obj runtime.Object) error {
+	patch, err := yaml.YAMLToJSON([]byte(override.Patch))
+	if err != nil {
+		return fmt.Errorf("error parsing the patch: %v", err)
+	}
+	original, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	var patched []byte
+	switch override.Type {
+	case operatorv1alpha1.OverrideJSONPatch:
+		operations, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return fmt.Errorf("error decoding the JSON patch: %v", err)
+		}
+		if patched, err = operations.Apply(original); err != nil {
+			return fmt.Errorf("error applying the JSON patch: %v", err)
+		}
+	default:
+		if patched, err = strategicpatch.StrategicMergePatch(original, patch, obj); err != nil {
+			return fmt.Errorf("error applying the strategic merge patch: %v", err)
+		}
+	}
+
+	// The result is decoded into a new object, since decoding into the existing one would keep the fields
+	// the patch removes. Unknown fields are rejected, so that a misspelt field isn't silently dropped.
+	result := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(runtime.Object)
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(result); err != nil {
+		return fmt.Errorf("error decoding the patched object: %v", err)
+	}
+	before, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	after, err := meta.Accessor(result)
+	if err != nil {
+		return err
+	}
+	if after.GetName() != before.GetName() || after.GetNamespace() != before.GetNamespace() {
+		return errors.New("the patch can't change the name or namespace of the object")
+	}
+	reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(result).Elem())
+	return nil
+}
+
+// Returns the errors for the overrides that don't match an object the operator renders or can't be applied to it.
+// The overrides of an object are applied in order, as they are when it is reconciled.
+func validateOverrides(instance *operatorv1alpha1.CertManager, bundle *res.Bundle, ns string) field.ErrorList {
+	if len(instance.Spec.Overrides) == 0 {
+		return nil
+	}
+	objects := overridableObjects(instance, bundle, ns)
+	path := field.NewPath("spec", "overrides")
+
+	var errs field.ErrorList
+	for i, override := range instance.Spec.Overrides {
+		overridePath := path.Index(i)
+		var target *overridable
+		for j := range objects {
+			if objects[j].kind == override.Kind && objects[j].name == override.Name {
+				target = &objects[j]
+			}
+		}
+		if target == nil {
+			errs = append(errs, field.NotFound(overridePath.Child("name"), override.Kind+"/"+override.Name))
+			continue
+		}
+		if err := applyOverride(override, target.object); err != nil {
+			errs = append(errs, field.Invalid(overridePath.Child("patch"), override.Patch, err.Error()))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,161 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"testing"
+
+	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
+	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestApplyOverride(t *testing.T) {
+	var replicas int32 = 1
+	original := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "cert-manager-webhook", Namespace: "ibm-common-services", Labels: map[string]string{"app": "webhook"}},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "cert-manager-webhook", Image: "webhook:0.10.3"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		override operatorv1alpha1.Override
+		want     func(*appsv1.Deployment)
+		wantErr  bool
+	}{
+		{
+			name:     "strategic merge patch",
+			override: operatorv1alpha1.Override{Patch: "metadata:\n  labels:\n    team: security\nspec:\n  replicas: 2"},
+			want: func(deploy *appsv1.Deployment) {
+				deploy.Labels["team"] = "security"
+				*deploy.Spec.Replicas = 2
+			},
+		},
+		{
+			name: "strategic merge patch of a container by name",
+			override: operatorv1alpha1.Override{Type: operatorv1alpha1.OverrideStrategicMerge,
+				Patch: `{"spec":{"template":{"spec":{"containers":[{"name":"cert-manager-webhook","image":"webhook:custom"}]}}}}`},
+			want: func(deploy *appsv1.Deployment) {
+				deploy.Spec.Template.Spec.Containers[0].Image = "webhook:custom"
+			},
+		},
+		{
+			name: "json patch",
+			override: operatorv1alpha1.Override{Type: operatorv1alpha1.OverrideJSONPatch,
+				Patch: "- op: remove\n  path: /metadata/labels/app\n- op: replace\n  path: /spec/replicas\n  value: 3"},
+			want: func(deploy *appsv1.Deployment) {
+				deploy.Labels = map[string]string{}
+				*deploy.Spec.Replicas = 3
+			},
+		},
+		{
+			name:     "json patch of a missing path",
+			override: operatorv1alpha1.Override{Type: operatorv1alpha1.OverrideJSONPatch, Patch: `[{"op":"replace","path":"/spec/paused/value","value":true}]`},
+			wantErr:  true,
+		},
+		{
+			name:     "json patch that isn't a list of operations",
+			override: operatorv1alpha1.Override{Type: operatorv1alpha1.OverrideJSONPatch, Patch: "spec:\n  replicas: 2"},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown field",
+			override: operatorv1alpha1.Override{Patch: "spec:\n  replicaz: 2"},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown field added by a json patch",
+			override: operatorv1alpha1.Override{Type: operatorv1alpha1.OverrideJSONPatch, Patch: `[{"op":"add","path":"/spec/replicaz","value":2}]`},
+			wantErr:  true,
+		},
+		{
+			name:     "name change",
+			override: operatorv1alpha1.Override{Patch: "metadata:\n  name: other"},
+			wantErr:  true,
+		},
+		{
+			name:     "namespace change",
+			override: operatorv1alpha1.Override{Type: operatorv1alpha1.OverrideJSONPatch, Patch: `[{"op":"replace","path":"/metadata/namespace","value":"default"}]`},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid yaml",
+			override: operatorv1alpha1.Override{Patch: "spec: [replicas"},
+			wantErr:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deploy := original.DeepCopy()
+			err := applyOverride(test.override, deploy)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("applyOverride() error = %v, wantErr %v", err, test.wantErr)
+			}
+			want := original.DeepCopy()
+			if test.want != nil {
+				test.want(want)
+			}
+			if !equality.Semantic.DeepEqual(deploy, want) {
+				t.Errorf("applyOverride() = %+v, want %+v", deploy, want)
+			}
+		})
+	}
+}
+
+func TestValidateOverrides(t *testing.T) {
+	bundle, err := res.GetBundle("")
+	if err != nil {
+		t.Fatalf("GetBundle() error = %v", err)
+	}
+	instance := &operatorv1alpha1.CertManager{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: operatorv1alpha1.CertManagerSpec{
+			Overrides: []operatorv1alpha1.Override{
+				{Kind: "Deployment", Name: res.CertManagerWebhookName, Patch: "spec:\n  replicas: 2"},
+				{Kind: "Deployment", Name: "cert-manager-missing", Patch: "spec:\n  replicas: 2"},
+				{Kind: "Service", Name: res.CertManagerWebhookName, Patch: "spec:\n  portz: []"},
+			},
+		},
+	}
+	path := field.NewPath("spec", "overrides")
+	want := field.ErrorList{
+		field.NotFound(path.Index(1).Child("name"), "Deployment/cert-manager-missing"),
+		field.Invalid(path.Index(2).Child("patch"), instance.Spec.Overrides[2].Patch, ""),
+	}
+
+	errs := validateOverrides(instance, bundle, res.DeployNamespace)
+	if len(errs) != len(want) {
+		t.Fatalf("validateOverrides() = %v, want %d errors", errs, len(want))
+	}
+	for i := range want {
+		if errs[i].Type != want[i].Type || errs[i].Field != want[i].Field {
+			t.Errorf("validateOverrides()[%d] = %v, want %s at %s", i, errs[i], want[i].Type, want[i].Field)
+		}
+	}
+}
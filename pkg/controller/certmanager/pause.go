@@ -0,0 +1,49 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Returns true if the instance is paused, after reporting it in an event and in the instance's status.
+// Nothing is created, updated or removed while it is paused, not even when it is deleted.
+// Resuming the instance is reported in an event as well.
+func (r *ReconcileCertManager) paused(instance *operatorv1alpha1.CertManager) bool {
+	if !instance.Paused() {
+		if conditionTrue(instance.Status, operatorv1alpha1.ConditionPaused) {
+			r.updateEvent(instance, "cert-manager is managed by the operator again", corev1.EventTypeNormal, "Resumed")
+		}
+		return false
+	}
+
+	reason := "Unmanaged"
+	msg := "The CertManager is Unmanaged, cert-manager is not managed by the operator"
+	if instance.Spec.ManagementState != operatorv1alpha1.Unmanaged {
+		reason = "PausedAnnotation"
+		msg = "The CertManager is annotated with " + operatorv1alpha1.PausedAnnotation + ", cert-manager is not managed by the operator"
+	}
+	if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
+		msg += ". It is uninstalled once the CertManager is managed again."
+	}
+	log.Info("CertManager is paused, skipping reconcile", "reason", reason)
+	r.updateEvent(instance, msg, corev1.EventTypeNormal, "Paused")
+	r.updatePausedStatus(instance, reason, msg)
+	return true
+}
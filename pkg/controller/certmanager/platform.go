@@ -0,0 +1,198 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
+	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
+
+	admRegv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/version"
+	apiRegv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+)
+
+var clusterVersionGVR = schema.GroupVersionResource{Group: "config.openshift.io", Version: "v1", Resource: "clusterversions"}
+
+// Detects the platform of the cluster and records it in the instance's status along with how the webhook
+// is networked on it. The detection is best effort, what can't be read is left out of the status.
+func (r *ReconcileCertManager) detectPlatform(instance *operatorv1alpha1.CertManager, bundle *res.Bundle) error {
+	discovery := r.kubeclient.Discovery()
+	platform := &operatorv1alpha1.PlatformStatus{Type: operatorv1alpha1.PlatformKubernetes}
+	if version, err := discovery.ServerVersion(); err == nil {
+		platform.KubernetesVersion = version.GitVersion
+	} else {
+		log.V(1).Info("Error reading the Kubernetes version", "error", err)
+	}
+
+	// OpenShift 4 serves its cluster version through the config API, OpenShift 3 only has the security API
+	platform.SecurityContextConstraints = servesGroupVersion(discovery, "security.openshift.io/v1")
+	if servesGroupVersion(discovery, clusterVersionGVR.GroupVersion().String()) {
+		platform.Type = operatorv1alpha1.PlatformOpenShift
+		clusterVersion, err := r.dynclient.Resource(clusterVersionGVR).Get("version", metav1.GetOptions{})
+		if err == nil {
+			platform.OpenShiftVersion, _, _ = unstructured.NestedString(clusterVersion.Object, "status", "desired", "version")
+		} else {
+			log.V(1).Info("Error reading the OpenShift version", "error", err)
+		}
+	} else if platform.SecurityContextConstraints {
+		platform.Type = operatorv1alpha1.PlatformOpenShift
+	}
+
+	var previous *operatorv1alpha1.PlatformStatus
+	if instance.Status.Platform != nil {
+		previous = instance.Status.Platform
+		platform.APIServerReachesPods = previous.APIServerReachesPods
+	}
+	if reaches := r.apiServerReachesPods(instance, bundle); reaches != nil {
+		platform.APIServerReachesPods = reaches
+	}
+	platform.WebhookHostNetwork = webhookHostNetwork(instance.Spec, platform)
+
+	if reflect.DeepEqual(previous, platform) {
+		return nil
+	}
+	if previous == nil || previous.WebhookHostNetwork != platform.WebhookHostNetwork {
+		network := "pod"
+		if platform.WebhookHostNetwork {
+			network = "host"
+		}
+		r.updateEvent(instance, fmt.Sprintf("Running the webhook on the %s network of %s", network, platform.Type), corev1.EventTypeNormal, "WebhookNetworking")
+	}
+	instance.Status.Platform = platform
+	return r.client.Status().Update(context.TODO(), instance)
+}
+
+// Returns whether the API server reaches the webhook on the pod network, or nil when that can't be told.
+// It is only checked while the webhook runs on the pod network with a ready pod. The aggregator checks
+// the availability of the legacy webhook's apiservice, the v1 webhook is called with a dry run request.
+func (r *ReconcileCertManager) apiServerReachesPods(instance *operatorv1alpha1.CertManager, bundle *res.Bundle) *bool {
+	deploy := &appsv1.Deployment{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: res.CertManagerWebhookName, Namespace: r.ns}, deploy); err != nil {
+		return nil
+	}
+	if deploy.Spec.Template.Spec.HostNetwork || deploy.Status.ReadyReplicas == 0 {
+		return nil
+	}
+	if !bundle.APIService {
+		return r.webhookCallReached(instance, bundle)
+	}
+	apiSvc := &apiRegv1.APIService{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: res.APISvcName}, apiSvc); err != nil {
+		return nil
+	}
+	for _, condition := range apiSvc.Status.Conditions {
+		if condition.Type != apiRegv1.Available {
+			continue
+		}
+		reaches := condition.Status == apiRegv1.ConditionTrue
+		if reaches || condition.Reason == "FailedDiscoveryCheck" {
+			log.V(1).Info("Checked whether the API server reaches the webhook on the pod network", "reaches", reaches, "message", condition.Message)
+			return &reaches
+		}
+	}
+	return nil
+}
+
+// Creates a clusterissuer with a dry run, which the API server sends to the validating webhook, and returns
+// whether the API server reached the webhook. The outcome only tells when every issuer is sent to the webhook
+// and a call that fails is rejected, so nil is returned for a webhook that fails open or selects objects.
+func (r *ReconcileCertManager) webhookCallReached(instance *operatorv1alpha1.CertManager, bundle *res.Bundle) *bool {
+	validating := validatingWebhook(instance, bundle, r.ns)
+	applyOverrides(instance, "ValidatingWebhookConfiguration", validating.Name, validating)
+	for _, webhook := range validating.Webhooks {
+		if webhook.FailurePolicy == nil || *webhook.FailurePolicy != admRegv1beta1.Fail ||
+			(webhook.ObjectSelector != nil && !reflect.DeepEqual(*webhook.ObjectSelector, metav1.LabelSelector{})) {
+			return nil
+		}
+	}
+
+	issuer := &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{"selfSigned": map[string]interface{}{}}}}
+	issuer.SetAPIVersion(res.V1Group + "/v1")
+	issuer.SetKind("ClusterIssuer")
+	issuer.SetName(webhookCheckName)
+	clusterIssuerGVR := schema.GroupVersionResource{Group: res.V1Group, Version: "v1", Resource: "clusterissuers"}
+	_, err := r.dynclient.Resource(clusterIssuerGVR).Create(issuer, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	reached := webhookReached(err)
+	if reached != nil {
+		log.V(1).Info("Checked whether the API server reaches the webhook on the pod network", "reaches", *reached, "error", fmt.Sprint(err))
+	}
+	return reached
+}
+
+// webhookCheckName is the name of the clusterissuer the webhook is checked with, it is never stored
+const webhookCheckName = "ibm-cert-manager-operator-webhook-check"
+
+// Returns whether a request sent to a webhook that fails closed reached it, from the request's error.
+// Only a network error calling the webhook tells it wasn't reached, other errors, such as the webhook's
+// certificate not being trusted yet, return nil.
+func webhookReached(err error) *bool {
+	reached := true
+	// The object would only conflict with an existing one after the webhook admitted it
+	if err == nil || apiErrors.IsAlreadyExists(err) {
+		return &reached
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "failed calling webhook") {
+		return nil
+	}
+	for _, networkErr := range []string{"context deadline exceeded", "Client.Timeout exceeded", "i/o timeout", "connection refused", "no route to host"} {
+		if strings.Contains(msg, networkErr) {
+			reached = false
+			return &reached
+		}
+	}
+	return nil
+}
+
+// Returns true if the webhook should run on the host network. It runs on the pod network unless the API
+// server was found not to reach it there, which is only checked while it runs there, so the host network
+// is only used after the pod network was seen to fail. It always runs on the pod network of OpenShift 3.11.
+func webhookHostNetwork(spec operatorv1alpha1.CertManagerSpec, platform *operatorv1alpha1.PlatformStatus) bool {
+	if spec.WebhookConfig.HostNetwork != nil {
+		return *spec.WebhookConfig.HostNetwork
+	}
+	if spec.OCP311 || openShift3(platform) {
+		return false
+	}
+	return platform != nil && platform.APIServerReachesPods != nil && !*platform.APIServerReachesPods
+}
+
+// Returns true if the platform is OpenShift 3: it serves security context constraints but no OpenShift
+// version, and runs a Kubernetes version older than OpenShift 4
+func openShift3(platform *operatorv1alpha1.PlatformStatus) bool {
+	if platform == nil || !platform.SecurityContextConstraints || platform.OpenShiftVersion != "" {
+		return false
+	}
+	kubeVersion, err := version.ParseGeneric(platform.KubernetesVersion)
+	if err != nil {
+		return false
+	}
+	// OpenShift 3.11 is the last OpenShift 3 release and runs Kubernetes 1.11
+	return kubeVersion.LessThan(version.MustParseGeneric("1.12"))
+}
@@ -0,0 +1,131 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"errors"
+	"testing"
+
+	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestWebhookHostNetwork(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name     string
+		spec     operatorv1alpha1.CertManagerSpec
+		platform *operatorv1alpha1.PlatformStatus
+		want     bool
+	}{
+		{
+			name: "not detected yet",
+			want: false,
+		},
+		{
+			name:     "empty platform status",
+			platform: &operatorv1alpha1.PlatformStatus{},
+			want:     false,
+		},
+		{
+			name:     "kubernetes not checked yet",
+			platform: &operatorv1alpha1.PlatformStatus{Type: operatorv1alpha1.PlatformKubernetes, KubernetesVersion: "v1.20.0"},
+			want:     false,
+		},
+		{
+			name: "openshift 4 not checked yet",
+			platform: &operatorv1alpha1.PlatformStatus{Type: operatorv1alpha1.PlatformOpenShift, KubernetesVersion: "v1.20.0+bbbc079",
+				OpenShiftVersion: "4.7.0", SecurityContextConstraints: true},
+			want: false,
+		},
+		{
+			name:     "api server reaches pods",
+			platform: &operatorv1alpha1.PlatformStatus{Type: operatorv1alpha1.PlatformKubernetes, APIServerReachesPods: &yes},
+			want:     false,
+		},
+		{
+			name:     "api server doesn't reach pods",
+			platform: &operatorv1alpha1.PlatformStatus{Type: operatorv1alpha1.PlatformKubernetes, APIServerReachesPods: &no},
+			want:     true,
+		},
+		{
+			name: "openshift 3",
+			platform: &operatorv1alpha1.PlatformStatus{Type: operatorv1alpha1.PlatformOpenShift, KubernetesVersion: "v1.11.0+d4cacc0",
+				SecurityContextConstraints: true, APIServerReachesPods: &no},
+			want: false,
+		},
+		{
+			name:     "ocp311",
+			spec:     operatorv1alpha1.CertManagerSpec{OCP311: true},
+			platform: &operatorv1alpha1.PlatformStatus{APIServerReachesPods: &no},
+			want:     false,
+		},
+		{
+			name:     "explicit host network",
+			spec:     operatorv1alpha1.CertManagerSpec{WebhookConfig: operatorv1alpha1.WebhookSpec{HostNetwork: &yes}},
+			platform: &operatorv1alpha1.PlatformStatus{APIServerReachesPods: &yes},
+			want:     true,
+		},
+		{
+			name:     "explicit pod network",
+			spec:     operatorv1alpha1.CertManagerSpec{WebhookConfig: operatorv1alpha1.WebhookSpec{HostNetwork: &no}},
+			platform: &operatorv1alpha1.PlatformStatus{APIServerReachesPods: &no},
+			want:     false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := webhookHostNetwork(test.spec, test.platform); got != test.want {
+				t.Errorf("webhookHostNetwork() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWebhookReached(t *testing.T) {
+	yes, no := true, false
+	clusterIssuers := schema.GroupResource{Group: "cert-manager.io", Resource: "clusterissuers"}
+	callFailed := func(cause string) error {
+		return apiErrors.NewInternalError(errors.New(`failed calling webhook "webhook.cert-manager.io": Post ` +
+			`https://cert-manager-webhook.ibm-common-services.svc:443/validate?timeout=10s: ` + cause))
+	}
+	tests := []struct {
+		name string
+		err  error
+		want *bool
+	}{
+		{name: "admitted", want: &yes},
+		{name: "name taken", err: apiErrors.NewAlreadyExists(clusterIssuers, webhookCheckName), want: &yes},
+		{name: "timeout", err: callFailed("context deadline exceeded"), want: &no},
+		{name: "client timeout", err: callFailed("net/http: request canceled (Client.Timeout exceeded while awaiting headers)"), want: &no},
+		{name: "connection refused", err: callFailed("dial tcp 10.128.0.12:10260: connect: connection refused"), want: &no},
+		{name: "no route", err: callFailed("dial tcp 10.128.0.12:10260: connect: no route to host"), want: &no},
+		{name: "untrusted certificate", err: callFailed("x509: certificate signed by unknown authority")},
+		{name: "crd not installed", err: apiErrors.NewNotFound(clusterIssuers, "")},
+		{name: "forbidden", err: apiErrors.NewForbidden(clusterIssuers, webhookCheckName, errors.New("denied"))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := webhookReached(test.err)
+			if (got == nil) != (test.want == nil) || (got != nil && *got != *test.want) {
+				t.Errorf("webhookReached() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -25,38 +25,50 @@ import (
 	admRegv1beta1 "k8s.io/api/admissionregistration/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	apiRegv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
-func webhookPrereqs(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client, ns string) error {
-	if err := createRoleBinding(instance, scheme, client); err != nil {
+func webhookPrereqs(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client, admission *admissionClient,
+	recorder record.EventRecorder, bundle *res.Bundle, ns string) error {
+	if err := createRoleBinding(instance, scheme, client, recorder, ns); err != nil {
 		return err
 	}
-	if err := service(instance, scheme, client, ns); err != nil {
+	if err := labelExcludedNamespaces(client, instance.Spec.WebhookConfig.ExcludedNamespaces); err != nil {
 		return err
 	}
-	if err := apiService(instance, scheme, client, ns); err != nil {
+	if err := service(instance, scheme, client, recorder, bundle, ns); err != nil {
 		return err
 	}
-	if err := webhooks(instance, scheme, client); err != nil {
+	if bundle.APIService {
+		if err := apiService(instance, scheme, client, recorder, ns); err != nil {
+			return err
+		}
+	} else if err := removeAPIService(client); err != nil {
+		// Left over from a release whose webhook was served through the apiservice
+		return err
+	}
+	if err := webhooks(instance, scheme, admission, recorder, bundle, ns); err != nil {
 		return err
 	}
 	return nil
 }
 
-func removeWebhookPrereqs(client client.Client, ns string) error {
+func removeWebhookPrereqs(client client.Client, admission *admissionClient, ns string) error {
 	if err := removeSvc(client, ns); err != nil {
 		return err
 	}
 	if err := removeAPIService(client); err != nil {
 		return err
 	}
-	if err := removeWebhooks(client); err != nil {
+	if err := removeWebhooks(admission); err != nil {
 		return err
 	}
 	if err := removeRoleBinding(client); err != nil {
@@ -65,22 +77,38 @@ func removeWebhookPrereqs(client client.Client, ns string) error {
 	return nil
 }
 
-func apiService(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client, ns string) error {
+func apiService(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client, recorder record.EventRecorder, ns string) error {
+	desired := res.APIService(ns)
+	applyOverrides(instance, "APIService", desired.Name, desired)
 	apiSvc := &apiRegv1.APIService{}
 	err := client.Get(context.Background(), types.NamespacedName{Name: res.APISvcName, Namespace: ""}, apiSvc)
 	if err != nil && apiErrors.IsNotFound(err) {
 		// Create the apiservice spec
-		res.APIService.ResourceVersion = ""
-		var servingSecret = ns + "/" + res.WebhookServingSecret
-		res.APIService.Annotations = map[string]string{"certmanager.k8s.io/inject-ca-from-secret": servingSecret}
-		res.APIService.Spec.Service.Namespace = ns
-		if err := controllerutil.SetControllerReference(instance, res.APIService, scheme); err != nil {
+		if err := controllerutil.SetControllerReference(instance, desired, scheme); err != nil {
 			log.Error(err, "Error setting controller reference on api service")
 		}
-		err := client.Create(context.Background(), res.APIService)
+		err := client.Create(context.Background(), desired)
 		if err != nil {
 			return err
 		}
+	} else if err != nil {
+		return err
+	} else {
+		// The CA bundle is injected by the cainjector and the port is defaulted by the API server
+		spec := desired.Spec
+		spec.CABundle = apiSvc.Spec.CABundle
+		if spec.Service != nil && apiSvc.Spec.Service != nil && spec.Service.Port == nil {
+			spec.Service.Port = apiSvc.Spec.Service.Port
+		}
+		if !equality.Semantic.DeepEqual(apiSvc.Spec, spec) || !isSubset(desired.Annotations, apiSvc.Annotations) {
+			log.V(1).Info("API service has drifted, updating it", "name", apiSvc.Name)
+			apiSvc.Spec = spec
+			apiSvc.Annotations = mergeMaps(apiSvc.Annotations, desired.Annotations)
+			if err := client.Update(context.Background(), apiSvc); err != nil {
+				return err
+			}
+			driftReverted(recorder, instance, "APIService", apiSvc.Name)
+		}
 	}
 	return nil
 }
@@ -100,80 +128,327 @@ func removeAPIService(client client.Client) error {
 	return nil
 }
 
-func webhooks(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client) error {
+// Creates or updates the webhook configurations, which are served as admissionregistration v1 when the API server supports it
+func webhooks(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, admission *admissionClient, recorder record.EventRecorder, bundle *res.Bundle, ns string) error {
+	desiredMutating := mutatingWebhook(instance, bundle, ns)
+	applyOverrides(instance, "MutatingWebhookConfiguration", desiredMutating.Name, desiredMutating)
 	mutating := &admRegv1beta1.MutatingWebhookConfiguration{}
-	err := client.Get(context.Background(), types.NamespacedName{Name: res.CertManagerWebhookName, Namespace: ""}, mutating)
+	err := admission.get(types.NamespacedName{Name: res.CertManagerWebhookName, Namespace: ""}, mutating)
 	if err != nil && apiErrors.IsNotFound(err) {
 		// Create the mutating webhook spec
-		res.MutatingWebhook.ResourceVersion = ""
-		if err := controllerutil.SetControllerReference(instance, res.MutatingWebhook, scheme); err != nil {
+		if err := controllerutil.SetControllerReference(instance, desiredMutating, scheme); err != nil {
 			log.Error(err, "Error setting controller reference on mutating webhook")
 		}
-		err := client.Create(context.Background(), res.MutatingWebhook)
+		err := admission.create(desiredMutating)
 		if err != nil {
 			return err
 		}
+	} else if err != nil {
+		return err
+	} else {
+		desired := mutatingWebhookDefaults(desiredMutating.Webhooks, mutating.Webhooks)
+		if !equality.Semantic.DeepEqual(mutating.Webhooks, desired) ||
+			!isSubset(desiredMutating.Labels, mutating.Labels) ||
+			!isSubset(desiredMutating.Annotations, mutating.Annotations) {
+			log.V(1).Info("Mutating webhook configuration has drifted, updating it", "name", mutating.Name)
+			mutating.Webhooks = desired
+			mutating.Labels = mergeMaps(mutating.Labels, desiredMutating.Labels)
+			mutating.Annotations = mergeMaps(mutating.Annotations, desiredMutating.Annotations)
+			if err := admission.update(mutating); err != nil {
+				return err
+			}
+			driftReverted(recorder, instance, "MutatingWebhookConfiguration", mutating.Name)
+		}
 	}
 
+	desiredValidating := validatingWebhook(instance, bundle, ns)
+	applyOverrides(instance, "ValidatingWebhookConfiguration", desiredValidating.Name, desiredValidating)
 	validating := &admRegv1beta1.ValidatingWebhookConfiguration{}
-	err = client.Get(context.Background(), types.NamespacedName{Name: res.CertManagerWebhookName, Namespace: ""}, validating)
+	err = admission.get(types.NamespacedName{Name: res.CertManagerWebhookName, Namespace: ""}, validating)
 	if err != nil && apiErrors.IsNotFound(err) {
 		// Create the validating webhook spec
-		res.ValidatingWebhook.ResourceVersion = ""
-		if err := controllerutil.SetControllerReference(instance, res.ValidatingWebhook, scheme); err != nil {
+		if err := controllerutil.SetControllerReference(instance, desiredValidating, scheme); err != nil {
 			log.Error(err, "Error setting controller reference on validating webhook")
 		}
-		err := client.Create(context.Background(), res.ValidatingWebhook)
+		err := admission.create(desiredValidating)
 		if err != nil {
 			return err
 		}
+	} else if err != nil {
+		return err
+	} else {
+		desired := validatingWebhookDefaults(desiredValidating.Webhooks, validating.Webhooks)
+		if !equality.Semantic.DeepEqual(validating.Webhooks, desired) ||
+			!isSubset(desiredValidating.Labels, validating.Labels) ||
+			!isSubset(desiredValidating.Annotations, validating.Annotations) {
+			log.V(1).Info("Validating webhook configuration has drifted, updating it", "name", validating.Name)
+			validating.Webhooks = desired
+			validating.Labels = mergeMaps(validating.Labels, desiredValidating.Labels)
+			validating.Annotations = mergeMaps(validating.Annotations, desiredValidating.Annotations)
+			if err := admission.update(validating); err != nil {
+				return err
+			}
+			driftReverted(recorder, instance, "ValidatingWebhookConfiguration", validating.Name)
+		}
 	}
 
 	return nil
 }
 
-func removeWebhooks(client client.Client) error {
+// Returns the mutating webhook configuration of the bundle with the CertManager's webhook settings
+func mutatingWebhook(instance *operatorv1alpha1.CertManager, bundle *res.Bundle, ns string) *admRegv1beta1.MutatingWebhookConfiguration {
+	desired := bundle.MutatingWebhook(ns)
+	for i := range desired.Webhooks {
+		webhook := &desired.Webhooks[i]
+		webhookSettings(instance.Spec.WebhookConfig, &webhook.FailurePolicy, &webhook.TimeoutSeconds, &webhook.NamespaceSelector, &webhook.ObjectSelector)
+	}
+	return desired
+}
+
+// Returns the validating webhook configuration of the bundle with the CertManager's webhook settings
+func validatingWebhook(instance *operatorv1alpha1.CertManager, bundle *res.Bundle, ns string) *admRegv1beta1.ValidatingWebhookConfiguration {
+	desired := bundle.ValidatingWebhook(ns)
+	for i := range desired.Webhooks {
+		webhook := &desired.Webhooks[i]
+		webhookSettings(instance.Spec.WebhookConfig, &webhook.FailurePolicy, &webhook.TimeoutSeconds, &webhook.NamespaceSelector, &webhook.ObjectSelector)
+	}
+	return desired
+}
+
+// Applies the CertManager's webhook settings to the fields of a mutating or validating webhook.
+// The selectors are always set, even when empty, so that removing a setting is reconciled
+// instead of the existing selector being kept as an API server default.
+func webhookSettings(spec operatorv1alpha1.WebhookSpec, failurePolicy **admRegv1beta1.FailurePolicyType, timeoutSeconds **int32,
+	namespaceSelector, objectSelector **metav1.LabelSelector) {
+	if spec.FailurePolicy != nil {
+		policy := *spec.FailurePolicy
+		*failurePolicy = &policy
+	}
+	if spec.TimeoutSeconds != nil {
+		timeout := *spec.TimeoutSeconds
+		*timeoutSeconds = &timeout
+	}
+
+	nsSelector := mergeSelectors(*namespaceSelector, spec.NamespaceSelector)
+	if len(spec.ExcludedNamespaces) > 0 {
+		nsSelector.MatchExpressions = append(nsSelector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      namespaceNameLabel,
+			Operator: metav1.LabelSelectorOpNotIn,
+			Values:   spec.ExcludedNamespaces,
+		})
+	}
+	*namespaceSelector = nsSelector
+	*objectSelector = mergeSelectors(*objectSelector, spec.ObjectSelector)
+}
+
+// namespaceNameLabel is set to the name of every namespace by the API server from Kubernetes 1.21 on
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// Labels the excluded namespaces with their names, which older API servers don't do, so that the
+// webhook's namespace selector excludes them. Namespaces that don't exist yet are labelled once they are created.
+func labelExcludedNamespaces(client client.Client, excluded []string) error {
+	for _, name := range excluded {
+		namespace := &corev1.Namespace{}
+		if err := client.Get(context.Background(), types.NamespacedName{Name: name}, namespace); err != nil {
+			if apiErrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if namespace.Labels[namespaceNameLabel] == name {
+			continue
+		}
+		log.V(1).Info("Labelling excluded namespace with its name", "namespace", name)
+		namespace.Labels = mergeMaps(namespace.Labels, map[string]string{namespaceNameLabel: name})
+		if err := client.Update(context.Background(), namespace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns a selector that requires everything both selectors require
+func mergeSelectors(first, second *metav1.LabelSelector) *metav1.LabelSelector {
+	merged := &metav1.LabelSelector{}
+	for _, selector := range []*metav1.LabelSelector{first, second} {
+		if selector == nil {
+			continue
+		}
+		if len(selector.MatchLabels) > 0 {
+			merged.MatchLabels = mergeMaps(merged.MatchLabels, selector.MatchLabels)
+		}
+		for _, expr := range selector.MatchExpressions {
+			merged.MatchExpressions = append(merged.MatchExpressions, *expr.DeepCopy())
+		}
+	}
+	return merged
+}
+
+// Returns a copy of the desired webhooks with the fields that are left unset filled in from the
+// existing webhooks of the same name. These are the fields the API server defaults and the CA
+// bundle that the cainjector injects, which would otherwise always be seen as drift.
+func mutatingWebhookDefaults(desired, existing []admRegv1beta1.MutatingWebhook) []admRegv1beta1.MutatingWebhook {
+	webhooks := make([]admRegv1beta1.MutatingWebhook, len(desired))
+	for i := range desired {
+		webhook := *desired[i].DeepCopy()
+		for _, current := range existing {
+			if current.Name != webhook.Name {
+				continue
+			}
+			webhook.ClientConfig = clientConfigDefaults(webhook.ClientConfig, current.ClientConfig)
+			webhook.Rules = ruleDefaults(webhook.Rules, current.Rules)
+			if webhook.FailurePolicy == nil {
+				webhook.FailurePolicy = current.FailurePolicy
+			}
+			if webhook.MatchPolicy == nil {
+				webhook.MatchPolicy = current.MatchPolicy
+			}
+			if webhook.NamespaceSelector == nil {
+				webhook.NamespaceSelector = current.NamespaceSelector
+			}
+			if webhook.ObjectSelector == nil {
+				webhook.ObjectSelector = current.ObjectSelector
+			}
+			if webhook.SideEffects == nil {
+				webhook.SideEffects = current.SideEffects
+			}
+			if webhook.TimeoutSeconds == nil {
+				webhook.TimeoutSeconds = current.TimeoutSeconds
+			}
+			if webhook.AdmissionReviewVersions == nil {
+				webhook.AdmissionReviewVersions = current.AdmissionReviewVersions
+			}
+			if webhook.ReinvocationPolicy == nil {
+				webhook.ReinvocationPolicy = current.ReinvocationPolicy
+			}
+		}
+		webhooks[i] = webhook
+	}
+	return webhooks
+}
+
+// Same as mutatingWebhookDefaults, for validating webhooks
+func validatingWebhookDefaults(desired, existing []admRegv1beta1.ValidatingWebhook) []admRegv1beta1.ValidatingWebhook {
+	webhooks := make([]admRegv1beta1.ValidatingWebhook, len(desired))
+	for i := range desired {
+		webhook := *desired[i].DeepCopy()
+		for _, current := range existing {
+			if current.Name != webhook.Name {
+				continue
+			}
+			webhook.ClientConfig = clientConfigDefaults(webhook.ClientConfig, current.ClientConfig)
+			webhook.Rules = ruleDefaults(webhook.Rules, current.Rules)
+			if webhook.FailurePolicy == nil {
+				webhook.FailurePolicy = current.FailurePolicy
+			}
+			if webhook.MatchPolicy == nil {
+				webhook.MatchPolicy = current.MatchPolicy
+			}
+			if webhook.NamespaceSelector == nil {
+				webhook.NamespaceSelector = current.NamespaceSelector
+			}
+			if webhook.ObjectSelector == nil {
+				webhook.ObjectSelector = current.ObjectSelector
+			}
+			if webhook.SideEffects == nil {
+				webhook.SideEffects = current.SideEffects
+			}
+			if webhook.TimeoutSeconds == nil {
+				webhook.TimeoutSeconds = current.TimeoutSeconds
+			}
+			if webhook.AdmissionReviewVersions == nil {
+				webhook.AdmissionReviewVersions = current.AdmissionReviewVersions
+			}
+		}
+		webhooks[i] = webhook
+	}
+	return webhooks
+}
+
+func clientConfigDefaults(desired, existing admRegv1beta1.WebhookClientConfig) admRegv1beta1.WebhookClientConfig {
+	desired.CABundle = existing.CABundle
+	if desired.Service != nil && existing.Service != nil && desired.Service.Port == nil {
+		desired.Service.Port = existing.Service.Port
+	}
+	return desired
+}
+
+func ruleDefaults(desired, existing []admRegv1beta1.RuleWithOperations) []admRegv1beta1.RuleWithOperations {
+	if len(desired) != len(existing) {
+		return desired
+	}
+	for i := range desired {
+		if desired[i].Scope == nil {
+			desired[i].Scope = existing[i].Scope
+		}
+	}
+	return desired
+}
+
+func removeWebhooks(admission *admissionClient) error {
 	mutating := &admRegv1beta1.MutatingWebhookConfiguration{}
-	err := client.Get(context.Background(), types.NamespacedName{Name: res.CertManagerWebhookName, Namespace: ""}, mutating)
+	err := admission.get(types.NamespacedName{Name: res.CertManagerWebhookName, Namespace: ""}, mutating)
 	if err != nil {
 		if !apiErrors.IsNotFound(err) {
 			return err
 		}
 	} else {
-		if err := client.Delete(context.Background(), mutating); err != nil {
+		if err := admission.delete(mutating); err != nil {
 			return err
 		}
 	}
 
 	validating := &admRegv1beta1.ValidatingWebhookConfiguration{}
-	err = client.Get(context.Background(), types.NamespacedName{Name: res.CertManagerWebhookName, Namespace: ""}, validating)
+	err = admission.get(types.NamespacedName{Name: res.CertManagerWebhookName, Namespace: ""}, validating)
 	if err != nil {
 		if !apiErrors.IsNotFound(err) {
 			return err
 		}
 	} else {
-		if err := client.Delete(context.Background(), validating); err != nil {
+		if err := admission.delete(validating); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func service(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client, ns string) error {
+func service(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client, recorder record.EventRecorder, bundle *res.Bundle, ns string) error {
+	desired := bundle.WebhookSvc(ns)
+	applyOverrides(instance, "Service", desired.Name, desired)
 	svc := &corev1.Service{}
 	err := client.Get(context.Background(), types.NamespacedName{Name: res.CertManagerWebhookName, Namespace: ns}, svc)
 	if err != nil && apiErrors.IsNotFound(err) {
 		// Create the webhook service spec
-		res.WebhookSvc.ResourceVersion = ""
-		res.WebhookSvc.Spec.ClusterIP = ""
-		res.WebhookSvc.Namespace = ns
-		if err := controllerutil.SetControllerReference(instance, res.WebhookSvc, scheme); err != nil {
+		if err := controllerutil.SetControllerReference(instance, desired, scheme); err != nil {
 			log.Error(err, "Error setting controller reference on webhook's service")
 		}
-		err := client.Create(context.Background(), res.WebhookSvc)
+		err := client.Create(context.Background(), desired)
 		if err != nil {
 			return err
 		}
+	} else if err != nil {
+		return err
+	} else {
+		// The protocol of the ports is defaulted by the API server
+		ports := make([]corev1.ServicePort, len(desired.Spec.Ports))
+		for i, port := range desired.Spec.Ports {
+			if port.Protocol == "" {
+				port.Protocol = corev1.ProtocolTCP
+			}
+			ports[i] = port
+		}
+		if !equality.Semantic.DeepEqual(svc.Spec.Ports, ports) ||
+			!equality.Semantic.DeepEqual(svc.Spec.Selector, desired.Spec.Selector) ||
+			svc.Spec.Type != desired.Spec.Type {
+			log.V(1).Info("Webhook service has drifted, updating it", "name", svc.Name)
+			svc.Spec.Ports = ports
+			svc.Spec.Selector = desired.Spec.Selector
+			svc.Spec.Type = desired.Spec.Type
+			if err := client.Update(context.Background(), svc); err != nil {
+				return err
+			}
+			driftReverted(recorder, instance, "Service", svc.Name)
+		}
 	}
 	return nil
 }
@@ -193,19 +468,40 @@ func removeSvc(client client.Client, ns string) error {
 	return nil
 }
 
-func createRoleBinding(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client) error {
+func createRoleBinding(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client, recorder record.EventRecorder, ns string) error {
 	log.V(2).Info("Creating role binding")
+	desired := res.WebhookRoleBinding(ns)
+	applyOverrides(instance, "RoleBinding", desired.Name, desired)
+	if err := controllerutil.SetControllerReference(instance, desired, scheme); err != nil {
+		log.Error(err, "Error setting controller reference on rolebinding")
+	}
+
 	roleBinding := &rbacv1.RoleBinding{}
 	err := client.Get(context.Background(), types.NamespacedName{Name: res.CertManagerWebhookName, Namespace: "kube-system"}, roleBinding)
 	if err != nil && apiErrors.IsNotFound(err) {
-		res.WebhookRoleBinding.ResourceVersion = ""
-		if err := controllerutil.SetControllerReference(instance, res.WebhookRoleBinding, scheme); err != nil {
-			log.Error(err, "Error setting controller reference on rolebinding")
-		}
-		err := client.Create(context.Background(), res.WebhookRoleBinding)
+		err := client.Create(context.Background(), desired)
 		if err != nil {
 			return err
 		}
+	} else if err != nil {
+		return err
+	} else if !equality.Semantic.DeepEqual(roleBinding.RoleRef, desired.RoleRef) {
+		// The role ref of a binding can't be changed, so the binding is recreated instead
+		log.V(1).Info("Role binding role ref has drifted, recreating it", "name", roleBinding.Name)
+		if err := client.Delete(context.Background(), roleBinding); err != nil {
+			return err
+		}
+		if err := client.Create(context.Background(), desired); err != nil {
+			return err
+		}
+		driftReverted(recorder, instance, "RoleBinding", roleBinding.Name)
+	} else if !equality.Semantic.DeepEqual(roleBinding.Subjects, desired.Subjects) {
+		log.V(1).Info("Role binding subjects have drifted, updating them", "name", roleBinding.Name)
+		roleBinding.Subjects = desired.Subjects
+		if err := client.Update(context.Background(), roleBinding); err != nil {
+			return err
+		}
+		driftReverted(recorder, instance, "RoleBinding", roleBinding.Name)
 	}
 	return nil
 }
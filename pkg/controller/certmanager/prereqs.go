@@ -18,37 +18,42 @@ package certmanager
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
 	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
-	apiextensionclientsetv1beta1 "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1beta1"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	typedCorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 // Check all RBAC is ready for cert-manager
-func checkRbac(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client, ns string) error {
-	if rolesError := roles(instance, scheme, client, ns); rolesError != nil {
+func checkRbac(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client, recorder record.EventRecorder, bundle *res.Bundle, ns string) error {
+	if rolesError := roles(instance, scheme, client, recorder, bundle, ns); rolesError != nil {
 		return rolesError
 	}
 	return nil
 }
 
-func roles(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client, ns string) error {
+func roles(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client, recorder record.EventRecorder, bundle *res.Bundle, ns string) error {
 
-	if clusterRoleErr := createClusterRole(instance, scheme, client); clusterRoleErr != nil {
+	if clusterRoleErr := createClusterRole(instance, scheme, client, recorder, bundle); clusterRoleErr != nil {
 		return clusterRoleErr
 	}
-	if clusterRoleBindingErr := createClusterRoleBinding(instance, scheme, client, ns); clusterRoleBindingErr != nil {
+	if clusterRoleBindingErr := createClusterRoleBinding(instance, scheme, client, recorder, ns); clusterRoleBindingErr != nil {
 		return clusterRoleBindingErr
 	}
 	if serviceAccountErr := createServiceAccount(instance, scheme, client, ns); serviceAccountErr != nil {
@@ -57,39 +62,67 @@ func roles(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, clien
 	return nil
 }
 
-func createClusterRole(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client) error {
+func createClusterRole(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client, recorder record.EventRecorder, bundle *res.Bundle) error {
 	log.V(2).Info("Creating cluster role")
+	desired := bundle.ClusterRole()
+	applyOverrides(instance, "ClusterRole", desired.Name, desired)
 	clusterRole := &rbacv1.ClusterRole{}
 	err := client.Get(context.Background(), types.NamespacedName{Name: res.ClusterRoleName, Namespace: ""}, clusterRole)
 	if err != nil && apiErrors.IsNotFound(err) {
-		res.DefaultClusterRole.ResourceVersion = ""
-
-		if err := controllerutil.SetControllerReference(instance, res.DefaultClusterRole, scheme); err != nil {
+		if err := controllerutil.SetControllerReference(instance, desired, scheme); err != nil {
 			log.Error(err, "Error setting controller reference on clusterrole")
 		}
-		err := client.Create(context.Background(), res.DefaultClusterRole)
+		err := client.Create(context.Background(), desired)
 		if err != nil {
 			return err
 		}
+	} else if err != nil {
+		return err
+	} else if !equality.Semantic.DeepEqual(clusterRole.Rules, desired.Rules) {
+		log.V(1).Info("Cluster role rules have drifted, updating them", "name", clusterRole.Name)
+		clusterRole.Rules = desired.Rules
+		if err := client.Update(context.Background(), clusterRole); err != nil {
+			return err
+		}
+		driftReverted(recorder, instance, "ClusterRole", clusterRole.Name)
 	}
 	return nil
 }
 
-func createClusterRoleBinding(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client, namespace string) error {
+func createClusterRoleBinding(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client, recorder record.EventRecorder, namespace string) error {
 	log.V(2).Info("Creating cluster role binding")
-	clusterRoleBinding := &rbacv1.ClusterRoleBinding{}
+	desired := res.DefaultClusterRoleBinding(namespace)
+	applyOverrides(instance, "ClusterRoleBinding", desired.Name, desired)
+	if err := controllerutil.SetControllerReference(instance, desired, scheme); err != nil {
+		log.Error(err, "Error setting controller reference on clusterrolebinding")
+	}
 
+	clusterRoleBinding := &rbacv1.ClusterRoleBinding{}
 	err := client.Get(context.Background(), types.NamespacedName{Name: res.ClusterRoleName, Namespace: ""}, clusterRoleBinding)
 	if err != nil && apiErrors.IsNotFound(err) {
-		res.DefaultClusterRoleBinding.ResourceVersion = ""
-		res.DefaultClusterRoleBinding.Subjects[0].Namespace = namespace
-		if err := controllerutil.SetControllerReference(instance, res.DefaultClusterRoleBinding, scheme); err != nil {
-			log.Error(err, "Error setting controller reference on clusterrolebinding")
-		}
-		err := client.Create(context.Background(), res.DefaultClusterRoleBinding)
+		err := client.Create(context.Background(), desired)
 		if err != nil {
 			return err
 		}
+	} else if err != nil {
+		return err
+	} else if !equality.Semantic.DeepEqual(clusterRoleBinding.RoleRef, desired.RoleRef) {
+		// The role ref of a binding can't be changed, so the binding is recreated instead
+		log.V(1).Info("Cluster role binding role ref has drifted, recreating it", "name", clusterRoleBinding.Name)
+		if err := client.Delete(context.Background(), clusterRoleBinding); err != nil {
+			return err
+		}
+		if err := client.Create(context.Background(), desired); err != nil {
+			return err
+		}
+		driftReverted(recorder, instance, "ClusterRoleBinding", clusterRoleBinding.Name)
+	} else if !equality.Semantic.DeepEqual(clusterRoleBinding.Subjects, desired.Subjects) {
+		log.V(1).Info("Cluster role binding subjects have drifted, updating them", "name", clusterRoleBinding.Name)
+		clusterRoleBinding.Subjects = desired.Subjects
+		if err := client.Update(context.Background(), clusterRoleBinding); err != nil {
+			return err
+		}
+		driftReverted(recorder, instance, "ClusterRoleBinding", clusterRoleBinding.Name)
 	}
 
 	return nil
@@ -97,12 +130,11 @@ func createClusterRoleBinding(instance *operatorv1alpha1.CertManager, scheme *ru
 
 func createServiceAccount(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client client.Client, namespace string) error {
 	log.V(2).Info("Creating service account")
-	res.DefaultServiceAccount.ResourceVersion = ""
-	res.DefaultServiceAccount.Namespace = namespace
-	err := client.Create(context.Background(), res.DefaultServiceAccount)
-	if err := controllerutil.SetControllerReference(instance, res.DefaultServiceAccount, scheme); err != nil {
+	serviceAccount := res.DefaultServiceAccount(namespace)
+	if err := controllerutil.SetControllerReference(instance, serviceAccount, scheme); err != nil {
 		log.Error(err, "Error setting controller reference on service account")
 	}
+	err := client.Create(context.Background(), serviceAccount)
 	if err != nil {
 		if !apiErrors.IsAlreadyExists(err) {
 			log.V(2).Info("Error creating the service account, but was not an already exists error", "error message", err)
@@ -117,11 +149,12 @@ func checkNamespace(instance *operatorv1alpha1.CertManager, scheme *runtime.Sche
 	getOpt := metav1.GetOptions{}
 
 	if _, err := client.Get(res.DeployNamespace, getOpt); err != nil && apiErrors.IsNotFound(err) {
-		if err = controllerutil.SetControllerReference(instance, res.NamespaceDef, scheme); err != nil {
+		namespace := res.Namespace(res.DeployNamespace)
+		if err = controllerutil.SetControllerReference(instance, namespace, scheme); err != nil {
 			log.Error(err, "Error setting controller reference on namespace")
 		}
 		log.V(1).Info("cert-manager namespace does not exist, creating it", "error", err)
-		if _, err = client.Create(res.NamespaceDef); err != nil {
+		if _, err = client.Create(namespace); err != nil {
 			return err
 		}
 	} else if err != nil {
@@ -131,45 +164,83 @@ func checkNamespace(instance *operatorv1alpha1.CertManager, scheme *runtime.Sche
 	return nil
 }
 
-// Checks for the existence of all certmanager CRDs
-// Takes action to create them if they do not exist
-func checkCrds(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client apiextensionclientsetv1beta1.CustomResourceDefinitionInterface, name, namespace string) error {
+// Checks for the existence of all certmanager CRDs of the bundle's release
+// Takes action to create them if they do not exist, and updates them if their spec differs from the bundled one.
+// Updates that could lose data are refused, which is reported with a crdUpdateRefusedError once the other CRDs are checked.
+// The CRDs are served as apiextensions.k8s.io/v1 when the API server supports it.
+func checkCrds(instance *operatorv1alpha1.CertManager, scheme *runtime.Scheme, client *crdClient,
+	dynclient dynamic.Interface, recorder record.EventRecorder, bundle *res.Bundle) error {
 	var allErrors []string
-	listOptions := metav1.ListOptions{}
-	customResourcesList, err := client.List(listOptions)
+	var refused []string
+	customResourcesList, err := client.list()
 	if err != nil {
 		return err
 	}
 
-	existingResources := make(map[string]bool)
-	for _, item := range customResourcesList.Items {
-		if strings.Contains(item.Name, res.GroupVersion) {
-			existingResources[item.Name] = false
-		}
+	existingResources := make(map[string]*apiext.CustomResourceDefinition)
+	for i, item := range customResourcesList {
+		existingResources[item.Name] = &customResourcesList[i]
 	}
 
 	// Check that the CRDs we need match the ones we got from the cluster
-	for _, item := range res.CRDs {
-		crName := item + "." + res.GroupVersion
-		if _, ok := existingResources[crName]; !ok { // CRD wasn't found, create it
-			log.V(1).Info("Did not find custom resource, creating it now", "resource", item)
-			crd := res.CRDMap[item]
+	for _, crName := range bundle.CRDs {
+		crd, err := client.desired(bundle.CRD(crName))
+		if err != nil {
+			allErrors = append(allErrors, err.Error())
+			continue
+		}
+		live, ok := existingResources[crName]
+		if !ok { // CRD wasn't found, create it
+			log.V(1).Info("Did not find custom resource, creating it now", "resource", crName)
 
 			if err := controllerutil.SetControllerReference(instance, crd, scheme); err != nil {
 				log.Error(err, "Error setting controller reference on crd")
 			}
-			if _, err = client.Create(crd); err != nil {
+			if err = client.create(crd); err != nil {
 				allErrors = append(allErrors, err.Error())
 			}
+			continue
+		}
+
+		desired := crdSpecDefaults(crd.Spec, live.Spec)
+		if equality.Semantic.DeepEqual(live.Spec, desired) {
+			continue
 		}
+		risks, err := crdUpdateRisks(dynclient, live, desired)
+		if err != nil {
+			allErrors = append(allErrors, err.Error())
+			continue
+		}
+		if len(risks) > 0 {
+			log.Info("Refusing to update custom resource", "resource", crName, "reasons", risks)
+			refused = append(refused, risks...)
+			continue
+		}
+		log.V(1).Info("Custom resource differs from the bundled one, updating it", "resource", crName)
+		live.Spec = desired
+		live.Labels = mergeMaps(live.Labels, crd.Labels)
+		live.Annotations = mergeMaps(live.Annotations, crd.Annotations)
+		if err := client.update(live); err != nil {
+			allErrors = append(allErrors, err.Error())
+			continue
+		}
+		recorder.Event(instance, corev1.EventTypeNormal, "CRDUpdated", fmt.Sprintf("Updated CustomResourceDefinition %s to the bundled spec", crName))
 	}
 	if allErrors != nil {
 		return errors.New(strings.Join(allErrors, "\n"))
 	}
+	if refused != nil {
+		return &crdUpdateRefusedError{reasons: refused}
+	}
 	log.V(2).Info("Finished checking CRDs, no errors found")
 	return nil
 }
 
+// Records an event on the instance when a resource that was changed outside of the operator is reverted
+func driftReverted(recorder record.EventRecorder, instance *operatorv1alpha1.CertManager, kind, name string) {
+	recorder.Event(instance, corev1.EventTypeNormal, "DriftReverted", fmt.Sprintf("Reverted changes made to %s %s", kind, name))
+}
+
 // Removes the clusterrole and clusterrolebinding created by this operator
 func removeRoles(client client.Client) error {
 	// Delete the clusterrolebinding
@@ -178,7 +249,6 @@ func removeRoles(client client.Client) error {
 	err := client.Get(context.Background(), types.NamespacedName{Name: res.ClusterRoleName, Namespace: ""}, clusterRoleBinding)
 	if err != nil && apiErrors.IsNotFound(err) {
 		log.V(1).Info("Error getting cluster role binding", "msg", err)
-		return nil
 	} else if err == nil {
 		if err = client.Delete(context.Background(), clusterRoleBinding); err != nil {
 			log.V(1).Info("Error deleting cluster role binding", "name", clusterRoleBinding.Name, "error message", err)
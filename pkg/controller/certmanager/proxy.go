@@ -0,0 +1,212 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+
+	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
+	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
+
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var clusterProxyGVR = schema.GroupVersionResource{Group: "config.openshift.io", Version: "v1", Resource: "proxies"}
+
+// The OpenShift cluster's proxy is the one named cluster
+const clusterProxyName = "cluster"
+
+// Records in the instance's status the proxy cert-manager-controller and the acmesolver pods are
+// configured with, from the CertManager's spec or else from the OpenShift cluster. A change of the
+// proxy rolls the controller's pods, since the proxy is passed to it as environment variables. The
+// acmesolver pods get it from the operator's webhook when they are created.
+func (r *ReconcileCertManager) detectProxy(instance *operatorv1alpha1.CertManager) error {
+	var proxy *operatorv1alpha1.ProxyStatus
+	if instance.Spec.Proxy != nil {
+		proxy = &operatorv1alpha1.ProxyStatus{Source: operatorv1alpha1.ProxySourceCertManager, ProxySpec: *instance.Spec.Proxy}
+	} else if servesGroupVersion(r.kubeclient.Discovery(), clusterProxyGVR.GroupVersion().String()) {
+		clusterProxy, err := r.dynclient.Resource(clusterProxyGVR).Get(clusterProxyName, metav1.GetOptions{})
+		if err != nil && !apiErrors.IsNotFound(err) {
+			return err
+		}
+		if err == nil {
+			proxy = &operatorv1alpha1.ProxyStatus{Source: operatorv1alpha1.ProxySourceCluster}
+			// The status has the proxy in effect, with the cluster's networks added to noProxy
+			proxy.HTTPProxy, _, _ = unstructured.NestedString(clusterProxy.Object, "status", "httpProxy")
+			proxy.HTTPSProxy, _, _ = unstructured.NestedString(clusterProxy.Object, "status", "httpsProxy")
+			proxy.NoProxy, _, _ = unstructured.NestedString(clusterProxy.Object, "status", "noProxy")
+		}
+	}
+	if proxy != nil && (proxy.HTTPProxy != "" || proxy.HTTPSProxy != "") {
+		// The controller reaches the API server at its service, which mustn't go through the proxy
+		proxy.NoProxy = appendNoProxy(proxy.NoProxy, os.Getenv("KUBERNETES_SERVICE_HOST"))
+	} else {
+		proxy = nil
+	}
+
+	if reflect.DeepEqual(instance.Status.Proxy, proxy) {
+		return nil
+	}
+	if proxy == nil {
+		r.updateEvent(instance, "Removing the proxy from cert-manager-controller and the acmesolver pods", corev1.EventTypeNormal, "ProxyChanged")
+	} else {
+		r.updateEvent(instance, fmt.Sprintf("Configuring cert-manager-controller and the acmesolver pods with the proxy from the %s", proxy.Source), corev1.EventTypeNormal, "ProxyChanged")
+	}
+	instance.Status.Proxy = proxy
+	return r.client.Status().Update(context.TODO(), instance)
+}
+
+// Returns the comma separated list with the host added to it, unless it is already in it
+func appendNoProxy(noProxy, host string) string {
+	if host == "" {
+		return noProxy
+	}
+	for _, entry := range strings.Split(noProxy, ",") {
+		if strings.TrimSpace(entry) == host {
+			return noProxy
+		}
+	}
+	if noProxy == "" {
+		return host
+	}
+	return noProxy + "," + host
+}
+
+// Returns the environment variables that pass the proxy to a container
+func proxyEnv(proxy *operatorv1alpha1.ProxyStatus) []corev1.EnvVar {
+	if proxy == nil {
+		return nil
+	}
+	var env []corev1.EnvVar
+	for _, variable := range []corev1.EnvVar{
+		{Name: "HTTP_PROXY", Value: proxy.HTTPProxy},
+		{Name: "HTTPS_PROXY", Value: proxy.HTTPSProxy},
+		{Name: "NO_PROXY", Value: proxy.NoProxy},
+	} {
+		if variable.Value != "" {
+			env = append(env, variable)
+		}
+	}
+	return env
+}
+
+// acmesolverProxyInjector passes the proxy to the acmesolver pods, which cert-manager creates
+// without a way to set their environment
+type acmesolverProxyInjector struct {
+	client  client.Client
+	decoder *admission.Decoder
+}
+
+// Handle adds the proxy recorded in the CertManager's status to the containers of an acmesolver pod.
+// The pod is patched as unstructured, so that the patch doesn't touch fields the operator doesn't know of.
+func (i *acmesolverProxyInjector) Handle(ctx context.Context, req admission.Request) admission.Response {
+	pod := &unstructured.Unstructured{}
+	if err := i.decoder.Decode(req, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if !isAcmesolverPod(pod) {
+		return admission.Allowed("")
+	}
+	instance := &operatorv1alpha1.CertManager{}
+	if err := i.client.Get(ctx, types.NamespacedName{Name: "default"}, instance); err != nil {
+		if apiErrors.IsNotFound(err) {
+			return admission.Allowed("")
+		}
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	injected, err := injectProxyEnv(pod, instance.Status.Proxy)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if !injected {
+		return admission.Allowed("")
+	}
+	marshaled, err := json.Marshal(pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// Returns whether the pod has the label cert-manager sets on the acmesolver pods. API servers
+// older than 1.15 ignore the webhooks' object selector and send every pod that is created.
+func isAcmesolverPod(pod *unstructured.Unstructured) bool {
+	labels := pod.GetLabels()
+	for _, label := range res.AcmesolverLabels {
+		if labels[label] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// Adds the proxy's environment variables to the pod's containers, leaving those a container
+// already sets as they are. Returns whether any variable was added.
+func injectProxyEnv(pod *unstructured.Unstructured, proxy *operatorv1alpha1.ProxyStatus) (bool, error) {
+	env := proxyEnv(proxy)
+	if len(env) == 0 {
+		return false, nil
+	}
+	containers, found, err := unstructured.NestedSlice(pod.Object, "spec", "containers")
+	if err != nil || !found {
+		return false, err
+	}
+	injected := false
+	for i := range containers {
+		container, ok := containers[i].(map[string]interface{})
+		if !ok {
+			return false, fmt.Errorf("container %d of the pod isn't an object", i)
+		}
+		current, _, err := unstructured.NestedSlice(container, "env")
+		if err != nil {
+			return false, err
+		}
+		set := map[string]bool{}
+		for _, variable := range current {
+			if variable, ok := variable.(map[string]interface{}); ok {
+				name, _, _ := unstructured.NestedString(variable, "name")
+				set[name] = true
+			}
+		}
+		for _, variable := range env {
+			if !set[variable.Name] {
+				current = append(current, map[string]interface{}{"name": variable.Name, "value": variable.Value})
+				injected = true
+			}
+		}
+		if err := unstructured.SetNestedSlice(container, current, "env"); err != nil {
+			return false, err
+		}
+	}
+	if !injected {
+		return false, nil
+	}
+	return true, unstructured.SetNestedSlice(pod.Object, containers, "spec", "containers")
+}
@@ -0,0 +1,135 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestInjectProxyEnv(t *testing.T) {
+	proxy := &operatorv1alpha1.ProxyStatus{
+		Source:    operatorv1alpha1.ProxySourceCertManager,
+		ProxySpec: operatorv1alpha1.ProxySpec{HTTPProxy: "http://proxy:3128", NoProxy: "10.0.0.1"},
+	}
+	tests := []struct {
+		name       string
+		proxy      *operatorv1alpha1.ProxyStatus
+		containers []interface{}
+		want       []interface{}
+		injected   bool
+	}{
+		{
+			name:       "no proxy",
+			containers: []interface{}{map[string]interface{}{"name": "acmesolver"}},
+			want:       []interface{}{map[string]interface{}{"name": "acmesolver"}},
+		},
+		{
+			name:       "container without env",
+			proxy:      proxy,
+			containers: []interface{}{map[string]interface{}{"name": "acmesolver"}},
+			want: []interface{}{map[string]interface{}{"name": "acmesolver", "env": []interface{}{
+				map[string]interface{}{"name": "HTTP_PROXY", "value": "http://proxy:3128"},
+				map[string]interface{}{"name": "NO_PROXY", "value": "10.0.0.1"},
+			}}},
+			injected: true,
+		},
+		{
+			name:  "variable already set",
+			proxy: proxy,
+			containers: []interface{}{map[string]interface{}{"name": "acmesolver", "env": []interface{}{
+				map[string]interface{}{"name": "NO_PROXY", "value": "example.com"},
+			}}},
+			want: []interface{}{map[string]interface{}{"name": "acmesolver", "env": []interface{}{
+				map[string]interface{}{"name": "NO_PROXY", "value": "example.com"},
+				map[string]interface{}{"name": "HTTP_PROXY", "value": "http://proxy:3128"},
+			}}},
+			injected: true,
+		},
+		{
+			name:  "all variables already set",
+			proxy: proxy,
+			containers: []interface{}{map[string]interface{}{"name": "acmesolver", "env": []interface{}{
+				map[string]interface{}{"name": "HTTP_PROXY", "value": "http://other:3128"},
+				map[string]interface{}{"name": "NO_PROXY", "value": "example.com"},
+			}}},
+			want: []interface{}{map[string]interface{}{"name": "acmesolver", "env": []interface{}{
+				map[string]interface{}{"name": "HTTP_PROXY", "value": "http://other:3128"},
+				map[string]interface{}{"name": "NO_PROXY", "value": "example.com"},
+			}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"containers": test.containers},
+			}}
+			injected, err := injectProxyEnv(pod, test.proxy)
+			if err != nil {
+				t.Fatalf("injectProxyEnv() error = %v", err)
+			}
+			if injected != test.injected {
+				t.Errorf("injectProxyEnv() = %v, want %v", injected, test.injected)
+			}
+			got, _, _ := unstructured.NestedSlice(pod.Object, "spec", "containers")
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("containers = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsAcmesolverPod(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   bool
+	}{
+		{
+			name: "no labels",
+			want: false,
+		},
+		{
+			name:   "legacy solver",
+			labels: map[string]string{"certmanager.k8s.io/acme-http01-solver": "true"},
+			want:   true,
+		},
+		{
+			name:   "v1 solver",
+			labels: map[string]string{"acme.cert-manager.io/http01-solver": "true"},
+			want:   true,
+		},
+		{
+			name:   "label not true",
+			labels: map[string]string{"acme.cert-manager.io/http01-solver": "false"},
+			want:   false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &unstructured.Unstructured{Object: map[string]interface{}{}}
+			pod.SetLabels(test.labels)
+			if got := isAcmesolverPod(pod); got != test.want {
+				t.Errorf("isAcmesolverPod() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
+	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// componentError is an error that occurred while deploying a single component
+type componentError struct {
+	deployment string
+	err        error
+}
+
+func (e *componentError) Error() string {
+	return e.err.Error()
+}
+
+// component pairs the name a cert-manager component is reported as with its deployment
+type component struct {
+	name       string
+	deployment string
+}
+
+// Returns the components that are expected to be deployed for the instance
+func expectedComponents(instance *operatorv1alpha1.CertManager) []component {
+	components := []component{
+		{name: "controller", deployment: res.CertManagerControllerName},
+		{name: "configmapWatcher", deployment: res.ConfigmapWatcherName},
+	}
+	if instance.Spec.Webhook {
+		components = append(components,
+			component{name: "cainjector", deployment: res.CertManagerCainjectorName},
+			component{name: "webhook", deployment: res.CertManagerWebhookName})
+	}
+	return components
+}
+
+// Records the outcome of a reconcile in the instance's status. The health of each component
+// is read from its deployment, so the status only reports cert-manager as available once
+// all of the pods are ready rather than as soon as the deployments are created.
+// crdErr is set when updating the CRDs was refused, which doesn't stop cert-manager from being deployed.
+// overrideErrs are the overrides that were skipped, the overrides aren't reported for an unsupported version.
+func (r *ReconcileCertManager) updateStatus(instance *operatorv1alpha1.CertManager, overrideErrs field.ErrorList, crdErr, prereqErr, deployErr error) {
+	status := instance.Status.DeepCopy()
+	generation := instance.Generation
+	status.ObservedGeneration = generation
+
+	available := r.updateComponentsStatus(instance, status, deployErr)
+	setCondition(status, generation, operatorv1alpha1.ConditionPaused, corev1.ConditionFalse, "Managed", "")
+	if _, err := res.GetBundle(instance.Spec.Version); err == nil {
+		if len(overrideErrs) > 0 {
+			setCondition(status, generation, operatorv1alpha1.ConditionOverridesApplied, corev1.ConditionFalse, "InvalidOverrides", overrideErrs.ToAggregate().Error())
+		} else {
+			setCondition(status, generation, operatorv1alpha1.ConditionOverridesApplied, corev1.ConditionTrue, "AsExpected", "")
+		}
+	}
+
+	if prereqErr != nil {
+		setCondition(status, generation, operatorv1alpha1.ConditionPrereqsMet, corev1.ConditionFalse, "PrereqsFailed", prereqErr.Error())
+	} else {
+		setCondition(status, generation, operatorv1alpha1.ConditionPrereqsMet, corev1.ConditionTrue, "PrereqsMet", "All prerequisites for deploying cert-manager service found")
+	}
+
+	if crdErr != nil {
+		setCondition(status, generation, operatorv1alpha1.ConditionCRDsUpToDate, corev1.ConditionFalse, "UpdateRefused", crdErr.Error())
+	} else if prereqErr == nil {
+		setCondition(status, generation, operatorv1alpha1.ConditionCRDsUpToDate, corev1.ConditionTrue, "UpToDate", "All CRDs match the bundled definitions")
+	}
+
+	switch {
+	case prereqErr != nil:
+		setCondition(status, generation, operatorv1alpha1.ConditionDegraded, corev1.ConditionTrue, "PrereqsFailed", prereqErr.Error())
+	case deployErr != nil:
+		setCondition(status, generation, operatorv1alpha1.ConditionDegraded, corev1.ConditionTrue, "DeployFailed", deployErr.Error())
+	case crdErr != nil:
+		setCondition(status, generation, operatorv1alpha1.ConditionDegraded, corev1.ConditionTrue, "CRDUpdateRefused", crdErr.Error())
+	default:
+		setCondition(status, generation, operatorv1alpha1.ConditionDegraded, corev1.ConditionFalse, "AsExpected", "")
+	}
+
+	switch {
+	case prereqErr != nil:
+		status.OverallStatus = "Error deploying cert-manager, prereqs not met"
+	case deployErr != nil:
+		status.OverallStatus = "Error deploying cert-manager"
+	case available:
+		status.OverallStatus = "Successfully deployed cert-manager"
+	default:
+		status.OverallStatus = "Deploying cert-manager"
+	}
+
+	r.writeStatus(instance, status)
+}
+
+// Records the status of a paused instance. The components are still read from their deployments,
+// while the conditions reporting the outcome of the last reconcile are kept as they were.
+func (r *ReconcileCertManager) updatePausedStatus(instance *operatorv1alpha1.CertManager, reason, message string) {
+	status := instance.Status.DeepCopy()
+	r.updateComponentsStatus(instance, status, nil)
+	setCondition(status, instance.Generation, operatorv1alpha1.ConditionPaused, corev1.ConditionTrue, reason, message)
+	status.OverallStatus = "Paused, cert-manager is not managed by the operator"
+	r.writeStatus(instance, status)
+}
+
+// Reads the status of every expected component into the status and sets the Available and
+// Progressing conditions from it. Returns true if all of the components are available.
+func (r *ReconcileCertManager) updateComponentsStatus(instance *operatorv1alpha1.CertManager, status *operatorv1alpha1.CertManagerStatus, deployErr error) bool {
+	available := true
+	progressing := false
+	status.Components = nil
+	for _, c := range expectedComponents(instance) {
+		componentStatus, deploy := r.componentStatus(c, deployErr)
+		status.Components = append(status.Components, componentStatus)
+		if c.deployment == res.CertManagerControllerName && deploy != nil {
+			status.AcmesolverImage = acmesolverImage(deploy)
+		}
+		// A component scaled to 0 replicas on purpose has all of its replicas ready
+		if deploy == nil || componentStatus.ReadyReplicas < componentStatus.DesiredReplicas {
+			available = false
+		}
+		if deploy != nil && rollingOut(deploy) {
+			progressing = true
+		}
+	}
+
+	generation := instance.Generation
+	if progressing {
+		setCondition(status, generation, operatorv1alpha1.ConditionProgressing, corev1.ConditionTrue, "RollingOut", "One or more cert-manager components are rolling out")
+	} else {
+		setCondition(status, generation, operatorv1alpha1.ConditionProgressing, corev1.ConditionFalse, "AsExpected", "")
+	}
+
+	if available {
+		setCondition(status, generation, operatorv1alpha1.ConditionAvailable, corev1.ConditionTrue, "AllReplicasReady", "All cert-manager components are ready")
+	} else {
+		setCondition(status, generation, operatorv1alpha1.ConditionAvailable, corev1.ConditionFalse, "ReplicasNotReady", "One or more cert-manager components are not ready")
+	}
+	return available
+}
+
+// Updates the instance's status if it changed
+func (r *ReconcileCertManager) writeStatus(instance *operatorv1alpha1.CertManager, status *operatorv1alpha1.CertManagerStatus) {
+	if !reflect.DeepEqual(instance.Status, *status) {
+		instance.Status = *status
+		if err := r.client.Status().Update(context.TODO(), instance); err != nil {
+			log.Error(err, "Error updating instance status")
+		}
+	}
+}
+
+// Returns true if the condition of the given type is true on the status
+func conditionTrue(status operatorv1alpha1.CertManagerStatus, condType operatorv1alpha1.ConditionType) bool {
+	for _, condition := range status.Conditions {
+		if condition.Type == condType {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// Reads the status of a component from its deployment. The deployment is nil if it was not found.
+func (r *ReconcileCertManager) componentStatus(c component, deployErr error) (operatorv1alpha1.ComponentStatus, *appsv1.Deployment) {
+	componentStatus := operatorv1alpha1.ComponentStatus{
+		Name:       c.name,
+		Deployment: c.deployment,
+	}
+	var failed *componentError
+	if errors.As(deployErr, &failed) && failed.deployment == c.deployment {
+		componentStatus.LastError = failed.Error()
+	}
+
+	deploy := &appsv1.Deployment{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: c.deployment, Namespace: r.ns}, deploy); err != nil {
+		if componentStatus.LastError == "" {
+			if apiErrors.IsNotFound(err) {
+				componentStatus.LastError = "Deployment not found"
+			} else {
+				componentStatus.LastError = err.Error()
+			}
+		}
+		return componentStatus, nil
+	}
+
+	componentStatus.DesiredReplicas = 1
+	if deploy.Spec.Replicas != nil {
+		componentStatus.DesiredReplicas = *deploy.Spec.Replicas
+	}
+	componentStatus.ReadyReplicas = deploy.Status.ReadyReplicas
+	if len(deploy.Spec.Template.Spec.Containers) > 0 {
+		componentStatus.Image = deploy.Spec.Template.Spec.Containers[0].Image
+	}
+	return componentStatus, deploy
+}
+
+// Returns the acmesolver image the cert-manager-controller deployment is configured with
+func acmesolverImage(deploy *appsv1.Deployment) string {
+	if len(deploy.Spec.Template.Spec.Containers) == 0 {
+		return ""
+	}
+	for _, arg := range deploy.Spec.Template.Spec.Containers[0].Args {
+		if strings.HasPrefix(arg, res.AcmesolverImageArg) {
+			return strings.TrimPrefix(arg, res.AcmesolverImageArg)
+		}
+	}
+	return ""
+}
+
+// Returns true if the deployment has not finished rolling out its latest pod template
+func rollingOut(deploy *appsv1.Deployment) bool {
+	desired := int32(1)
+	if deploy.Spec.Replicas != nil {
+		desired = *deploy.Spec.Replicas
+	}
+	return deploy.Status.ObservedGeneration < deploy.Generation ||
+		deploy.Status.UpdatedReplicas < desired ||
+		deploy.Status.Replicas > deploy.Status.UpdatedReplicas ||
+		deploy.Status.AvailableReplicas < desired
+}
+
+// Sets the condition of the given type on the status, only moving its
+// last transition time forward when the condition's status changes
+func setCondition(status *operatorv1alpha1.CertManagerStatus, generation int64, condType operatorv1alpha1.ConditionType, condStatus corev1.ConditionStatus, reason, message string) {
+	condition := operatorv1alpha1.CertManagerCondition{
+		Type:               condType,
+		Status:             condStatus,
+		ObservedGeneration: generation,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+	for i, existing := range status.Conditions {
+		if existing.Type != condType {
+			continue
+		}
+		if existing.Status == condStatus {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		status.Conditions[i] = condition
+		return
+	}
+	status.Conditions = append(status.Conditions, condition)
+}
@@ -0,0 +1,192 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"context"
+
+	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
+	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
+
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	admRegv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	apiRegv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Tears down cert-manager according to the instance's uninstall policy. This runs while the
+// instance's finalizer is still in place, before its owned resources are garbage collected.
+func (r *ReconcileCertManager) uninstall(instance *operatorv1alpha1.CertManager, bundle *res.Bundle) error {
+	policy := instance.Spec.UninstallPolicy
+	if policy == "" {
+		policy = operatorv1alpha1.UninstallDeleteOperands
+	}
+	log.Info("Uninstalling cert-manager", "uninstall policy", policy)
+
+	if policy == operatorv1alpha1.UninstallRetain {
+		// Keep everything, but stop it from being garbage collected along with the instance
+		for _, owned := range r.ownedResources(bundle) {
+			if err := orphan(r.client, instance, owned.obj, owned.key); err != nil {
+				return err
+			}
+		}
+		if err := r.orphanMonitoring(instance); err != nil {
+			return err
+		}
+		return r.orphanDefaultIssuers(instance, bundle)
+	}
+
+	// The default issuers are removed while cert-manager's webhook still admits the changes
+	if err := r.removeDefaultIssuers(instance, bundle, nil); err != nil {
+		return err
+	}
+	// The webhooks are removed before the deployments so that requests aren't sent to a webhook that is going away
+	if err := removeWebhooks(r.admission); err != nil {
+		return err
+	}
+	if err := removeAPIService(r.client); err != nil {
+		return err
+	}
+	for _, name := range []string{res.CertManagerWebhookName, res.CertManagerCainjectorName, res.CertManagerControllerName, res.ConfigmapWatcherName} {
+		if err := removeDeploy(r.kubeclient, name, r.ns); err != nil && !apiErrors.IsNotFound(err) {
+			return err
+		}
+		if err := removeDisruptionBudget(r.client, name, r.ns); err != nil {
+			return err
+		}
+	}
+	if err := removeSvc(r.client, r.ns); err != nil {
+		return err
+	}
+	if err := r.removeMonitoring(servesGroupVersion(r.kubeclient.Discovery(), monitoringv1.SchemeGroupVersion.String())); err != nil {
+		return err
+	}
+	if err := removeRoleBinding(r.client); err != nil {
+		return err
+	}
+	if err := removeRoles(r.client); err != nil {
+		return err
+	}
+	if err := removeServiceAccount(r.client, r.ns); err != nil {
+		return err
+	}
+
+	for _, item := range bundle.CRDs {
+		crd := r.crdclient.object()
+		key := types.NamespacedName{Name: item}
+		if policy == operatorv1alpha1.UninstallDeleteAll {
+			// Deleting the CRDs deletes every certificate, issuer and other resource of their kinds
+			if err := r.client.Get(context.TODO(), key, crd); err != nil {
+				if apiErrors.IsNotFound(err) {
+					continue
+				}
+				return err
+			}
+			if err := r.client.Delete(context.TODO(), crd); err != nil && !apiErrors.IsNotFound(err) {
+				return err
+			}
+			log.V(1).Info("Removed CRD", "name", key.Name)
+		} else if err := orphan(r.client, instance, crd, key); err != nil {
+			return err
+		}
+	}
+	log.Info("Finished uninstalling cert-manager", "uninstall policy", policy)
+	return nil
+}
+
+// ownedResource is a resource the operator creates for cert-manager
+type ownedResource struct {
+	obj runtime.Object
+	key types.NamespacedName
+}
+
+// Returns all of the resources the operator creates for the bundle's release of cert-manager
+func (r *ReconcileCertManager) ownedResources(bundle *res.Bundle) []ownedResource {
+	mutating, _ := r.admission.served(&admRegv1beta1.MutatingWebhookConfiguration{})
+	validating, _ := r.admission.served(&admRegv1beta1.ValidatingWebhookConfiguration{})
+	owned := []ownedResource{
+		{mutating, types.NamespacedName{Name: res.CertManagerWebhookName}},
+		{validating, types.NamespacedName{Name: res.CertManagerWebhookName}},
+		{&apiRegv1.APIService{}, types.NamespacedName{Name: res.APISvcName}},
+		{&corev1.Service{}, types.NamespacedName{Name: res.CertManagerWebhookName, Namespace: r.ns}},
+		{&corev1.Service{}, types.NamespacedName{Name: res.ControllerMetricsName, Namespace: r.ns}},
+		{&rbacv1.RoleBinding{}, types.NamespacedName{Name: res.CertManagerWebhookName, Namespace: "kube-system"}},
+		{&rbacv1.ClusterRoleBinding{}, types.NamespacedName{Name: res.ClusterRoleName}},
+		{&rbacv1.ClusterRole{}, types.NamespacedName{Name: res.ClusterRoleName}},
+		{&corev1.ServiceAccount{}, types.NamespacedName{Name: res.ServiceAccount, Namespace: r.ns}},
+	}
+	for _, name := range []string{res.CertManagerWebhookName, res.CertManagerCainjectorName, res.CertManagerControllerName, res.ConfigmapWatcherName} {
+		owned = append(owned, ownedResource{&appsv1.Deployment{}, types.NamespacedName{Name: name, Namespace: r.ns}})
+		owned = append(owned, ownedResource{&policyv1beta1.PodDisruptionBudget{}, types.NamespacedName{Name: name, Namespace: r.ns}})
+	}
+	for _, item := range bundle.CRDs {
+		owned = append(owned, ownedResource{r.crdclient.object(), types.NamespacedName{Name: item}})
+	}
+	return owned
+}
+
+// Removes the instance's owner reference from the object so that it isn't garbage
+// collected when the instance is deleted. Objects that don't exist are skipped.
+func orphan(client client.Client, instance *operatorv1alpha1.CertManager, obj runtime.Object, key types.NamespacedName) error {
+	if err := client.Get(context.TODO(), key, obj); err != nil {
+		if apiErrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	var refs []metav1.OwnerReference
+	for _, ref := range accessor.GetOwnerReferences() {
+		if ref.UID != instance.UID {
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) == len(accessor.GetOwnerReferences()) {
+		return nil
+	}
+	accessor.SetOwnerReferences(refs)
+	log.V(1).Info("Removing owner reference", "name", key.Name, "namespace", key.Namespace)
+	return client.Update(context.TODO(), obj)
+}
+
+func removeServiceAccount(client client.Client, ns string) error {
+	serviceAccount := &corev1.ServiceAccount{}
+	err := client.Get(context.Background(), types.NamespacedName{Name: res.ServiceAccount, Namespace: ns}, serviceAccount)
+	if err != nil {
+		if !apiErrors.IsNotFound(err) {
+			return err
+		}
+	} else {
+		if err := client.Delete(context.Background(), serviceAccount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
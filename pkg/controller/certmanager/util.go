@@ -16,6 +16,8 @@
 
 package certmanager
 
+import "k8s.io/client-go/discovery"
+
 func containsString(source []string, str string) bool {
 	for _, searchString := range source {
 		if searchString == str {
@@ -34,3 +36,24 @@ func removeString(source []string, str string) (result []string) {
 	}
 	return
 }
+
+// Returns the first map with all of the entries of the second map added to it
+func mergeMaps(first, second map[string]string) map[string]string {
+	if first == nil {
+		first = make(map[string]string, len(second))
+	}
+	for k, v := range second {
+		first[k] = v
+	}
+	return first
+}
+
+// Returns true if the API server serves the group version, falling back to an older
+// version is left to the caller
+func servesGroupVersion(client discovery.DiscoveryInterface, groupVersion string) bool {
+	if _, err := client.ServerResourcesForGroupVersion(groupVersion); err != nil {
+		log.V(1).Info("Group version not served", "group version", groupVersion, "error", err)
+		return false
+	}
+	return true
+}
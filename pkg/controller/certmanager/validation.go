@@ -0,0 +1,349 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
+	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// The grammar of image references, as parsed by docker/distribution's reference package
+const (
+	domainComponent = `(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])`
+	domain          = domainComponent + `(?:\.` + domainComponent + `)*(?::[0-9]+)?`
+	pathComponent   = `[a-z0-9]+(?:(?:[._]|__|[-]*)[a-z0-9]+)*`
+	imageName       = `(?:` + domain + `/)?` + pathComponent + `(?:/` + pathComponent + `)*`
+	imageTag        = `[\w][\w.-]{0,127}`
+	imageDigest     = `[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,}`
+)
+
+// imagePrefixRegexp matches a registry, optionally followed by a repository path, such as quay.io/opencloudio
+var imagePrefixRegexp = regexp.MustCompile(`^` + domain + `(?:/` + pathComponent + `)*$`)
+
+// imageRegexp matches a full image reference, with an optional tag and digest
+var imageRegexp = regexp.MustCompile(`^` + imageName + `(?::` + imageTag + `)?(?:@` + imageDigest + `)?$`)
+
+// tagSuffixRegexp matches what can be appended to an image tag
+var tagSuffixRegexp = regexp.MustCompile(`^[\w.-]*$`)
+
+// certManagerDefaulter fills in the defaults of CertManagers when they are created or updated
+type certManagerDefaulter struct {
+	decoder *admission.Decoder
+}
+
+// Handle sets the fields that are left empty to their defaults. The object is defaulted as
+// unstructured, so that the patch doesn't touch fields the operator doesn't know of.
+func (d *certManagerDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	obj := &unstructured.Unstructured{}
+	if err := d.decoder.Decode(req, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	defaults := map[string]string{
+		"version":         res.LegacyVersion,
+		"uninstallPolicy": string(operatorv1alpha1.UninstallDeleteOperands),
+		"managementState": string(operatorv1alpha1.Managed),
+	}
+	for name, value := range defaults {
+		if current, _, _ := unstructured.NestedString(obj.Object, "spec", name); current == "" {
+			if err := unstructured.SetNestedField(obj.Object, value, "spec", name); err != nil {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+		}
+	}
+	defaulted, err := json.Marshal(obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, defaulted)
+}
+
+// certManagerValidator rejects CertManagers that the operator can't deploy
+type certManagerValidator struct {
+	client  client.Client
+	decoder *admission.Decoder
+	ns      string
+}
+
+// Handle validates the CertManager. Updates that leave the spec as it is, such as those
+// to the finalizers, are always allowed so that an existing CertManager can be removed.
+func (v *certManagerValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	instance := &operatorv1alpha1.CertManager{}
+	if err := v.decoder.Decode(req, instance); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	var old *operatorv1alpha1.CertManager
+	if req.Operation == admissionv1beta1.Update {
+		old = &operatorv1alpha1.CertManager{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if !instance.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(old.Spec, instance.Spec) {
+			return admission.Allowed("")
+		}
+	}
+
+	errs := validateCertManager(instance, old)
+	errs = append(errs, v.validateResourceNamespace(instance, old)...)
+	errs = append(errs, validateExtras(instance, v.ns)...)
+	if bundle, err := res.GetBundle(instance.Spec.Version); err == nil {
+		errs = append(errs, validateOverrides(instance, bundle, v.ns)...)
+	}
+	if len(errs) > 0 {
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+	return admission.Allowed("")
+}
+
+// The namespace the cluster issuers' secrets are read from has to exist, which is only
+// checked when it is set or changed, so that the CertManager isn't stuck if it is removed later
+func (v *certManagerValidator) validateResourceNamespace(instance, old *operatorv1alpha1.CertManager) field.ErrorList {
+	ns := instance.Spec.ResourceNS
+	if ns == "" || (old != nil && old.Spec.ResourceNS == ns) || len(validation.IsDNS1123Label(ns)) > 0 {
+		return nil
+	}
+	path := field.NewPath("spec", "resourceNamespace")
+	err := v.client.Get(context.Background(), types.NamespacedName{Name: ns}, &corev1.Namespace{})
+	if apiErrors.IsNotFound(err) {
+		return field.ErrorList{field.NotFound(path, ns)}
+	} else if err != nil {
+		return field.ErrorList{field.InternalError(path, err)}
+	}
+	return nil
+}
+
+// Returns the errors in the CertManager's spec. The old CertManager is nil when it is created.
+func validateCertManager(instance, old *operatorv1alpha1.CertManager) field.ErrorList {
+	var errs field.ErrorList
+	if old == nil && instance.Name != "default" {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "name"), instance.Name, "Only one CertManager named default is allowed"))
+	}
+
+	spec := instance.Spec
+	path := field.NewPath("spec")
+	if _, err := res.GetBundle(spec.Version); err != nil {
+		errs = append(errs, field.NotSupported(path.Child("version"), spec.Version, res.Versions()))
+	}
+	if spec.ImageRegistry != "" && !imagePrefixRegexp.MatchString(strings.TrimRight(spec.ImageRegistry, "/")) {
+		errs = append(errs, field.Invalid(path.Child("imageRegistry"), spec.ImageRegistry, "must be a registry, optionally followed by a repository path"))
+	}
+	if !tagSuffixRegexp.MatchString(spec.ImagePostFix) {
+		errs = append(errs, field.Invalid(path.Child("imagePostFix"), spec.ImagePostFix, "must only contain letters, digits, '_', '.' and '-'"))
+	}
+	errs = append(errs, validateImage(path.Child("acmesolverImage"), spec.AcmesolverImage)...)
+	for i, mirror := range spec.ImageMirrors {
+		mirrorPath := path.Child("imageMirrors").Index(i)
+		if !imagePrefixRegexp.MatchString(strings.TrimRight(mirror.Source, "/")) {
+			errs = append(errs, field.Invalid(mirrorPath.Child("source"), mirror.Source, "must be a registry, optionally followed by a repository path"))
+		}
+		if !imagePrefixRegexp.MatchString(strings.TrimRight(mirror.Mirror, "/")) {
+			errs = append(errs, field.Invalid(mirrorPath.Child("mirror"), mirror.Mirror, "must be a registry, optionally followed by a repository path"))
+		}
+	}
+	for i, secret := range spec.ImagePullSecrets {
+		for _, msg := range validation.IsDNS1123Subdomain(secret.Name) {
+			errs = append(errs, field.Invalid(path.Child("imagePullSecrets").Index(i).Child("name"), secret.Name, msg))
+		}
+	}
+	if spec.ResourceNS != "" {
+		for _, msg := range validation.IsDNS1123Label(spec.ResourceNS) {
+			errs = append(errs, field.Invalid(path.Child("resourceNamespace"), spec.ResourceNS, msg))
+		}
+	}
+	if spec.Proxy != nil {
+		errs = append(errs, validateProxyURL(path.Child("proxy", "httpProxy"), spec.Proxy.HTTPProxy)...)
+		errs = append(errs, validateProxyURL(path.Child("proxy", "httpsProxy"), spec.Proxy.HTTPSProxy)...)
+	}
+	for i, ns := range spec.WebhookConfig.ExcludedNamespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, field.Invalid(path.Child("webhook", "excludedNamespaces").Index(i), ns, msg))
+		}
+	}
+
+	if spec.DefaultIssuers != nil {
+		errs = append(errs, validateDefaultIssuers(path.Child("defaultIssuers"), spec.DefaultIssuers, spec.Version)...)
+	}
+
+	errs = append(errs, validateComponent(path.Child("controller"), spec.ControllerConfig)...)
+	errs = append(errs, validateComponent(path.Child("webhook"), spec.WebhookConfig.ComponentSpec)...)
+	errs = append(errs, validateComponent(path.Child("cainjector"), spec.CainjectorConfig)...)
+	errs = append(errs, validateComponent(path.Child("configmapWatcher"), spec.ConfigmapWatcherConfig)...)
+	return errs
+}
+
+// cert-manager doesn't issue certificates valid for less than an hour
+const minCADuration = time.Hour
+
+func validateDefaultIssuers(path *field.Path, spec *operatorv1alpha1.DefaultIssuersSpec, version string) field.ErrorList {
+	var errs field.ErrorList
+	names := map[string]bool{res.SelfSignedIssuerName: true}
+	caIssuer := spec.CA.IssuerName
+	if caIssuer == "" {
+		caIssuer = res.CAIssuerName
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(caIssuer) {
+		errs = append(errs, field.Invalid(path.Child("ca", "issuerName"), caIssuer, msg))
+	}
+	if names[caIssuer] {
+		errs = append(errs, field.Duplicate(path.Child("ca", "issuerName"), caIssuer))
+	}
+	names[caIssuer] = true
+	if spec.CA.Duration != nil && spec.CA.Duration.Duration < minCADuration {
+		errs = append(errs, field.Invalid(path.Child("ca", "duration"), spec.CA.Duration.Duration.String(), "must be at least 1h"))
+	}
+
+	for i, issuer := range spec.ACME {
+		issuerPath := path.Child("acme").Index(i)
+		for _, msg := range validation.IsDNS1123Subdomain(issuer.Name) {
+			errs = append(errs, field.Invalid(issuerPath.Child("name"), issuer.Name, msg))
+		}
+		if names[issuer.Name] {
+			errs = append(errs, field.Duplicate(issuerPath.Child("name"), issuer.Name))
+		}
+		names[issuer.Name] = true
+		if server, err := url.Parse(issuer.Server); err != nil || server.Scheme != "https" || server.Host == "" {
+			errs = append(errs, field.Invalid(issuerPath.Child("server"), issuer.Server, "must be an https URL"))
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(issuer.PrivateKeySecretName) {
+			errs = append(errs, field.Invalid(issuerPath.Child("privateKeySecretName"), issuer.PrivateKeySecretName, msg))
+		}
+		if binding := issuer.ExternalAccountBinding; binding != nil {
+			bindingPath := issuerPath.Child("externalAccountBinding")
+			if version == "" || version == res.LegacyVersion {
+				errs = append(errs, field.Forbidden(bindingPath, "is not supported by cert-manager "+res.LegacyVersion))
+			}
+			for _, msg := range validation.IsDNS1123Subdomain(binding.KeySecretRef.Name) {
+				errs = append(errs, field.Invalid(bindingPath.Child("keySecretRef", "name"), binding.KeySecretRef.Name, msg))
+			}
+			if binding.KeySecretRef.Key == "" {
+				errs = append(errs, field.Required(bindingPath.Child("keySecretRef", "key"), ""))
+			}
+		}
+		for j, raw := range issuer.Solvers {
+			var solver map[string]interface{}
+			if err := json.Unmarshal(raw.Raw, &solver); err != nil || solver == nil {
+				errs = append(errs, field.Invalid(issuerPath.Child("solvers").Index(j), string(raw.Raw), "must be an object"))
+			}
+		}
+	}
+	return errs
+}
+
+// Checks that the proxy is an http or https URL with a host, as Go's HTTP client expects
+func validateProxyURL(path *field.Path, proxy string) field.ErrorList {
+	if proxy == "" {
+		return nil
+	}
+	parsed, err := url.Parse(proxy)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return field.ErrorList{field.Invalid(path, proxy, "must be an http or https URL, such as http://proxy.example.com:3128")}
+	}
+	return nil
+}
+
+func validateComponent(path *field.Path, component operatorv1alpha1.ComponentSpec) field.ErrorList {
+	errs := validateImage(path.Child("image"), component.Image)
+	if component.Resources == nil {
+		return errs
+	}
+	resourcesPath := path.Child("resources")
+	for name, quantity := range component.Resources.Limits {
+		if quantity.Sign() < 0 {
+			errs = append(errs, field.Invalid(resourcesPath.Child("limits").Key(string(name)), quantity.String(), "must not be negative"))
+		}
+	}
+	for name, quantity := range component.Resources.Requests {
+		if quantity.Sign() < 0 {
+			errs = append(errs, field.Invalid(resourcesPath.Child("requests").Key(string(name)), quantity.String(), "must not be negative"))
+		}
+	}
+	// The overrides are merged with the defaults, which only leaves a request above a limit that is set with it
+	resources := componentResources(component.Resources)
+	for name, request := range resources.Requests {
+		if limit, ok := resources.Limits[name]; ok && request.Cmp(limit) > 0 {
+			errs = append(errs, field.Invalid(resourcesPath.Child("requests").Key(string(name)), request.String(),
+				"must be less than or equal to the limit "+limit.String()))
+		}
+	}
+	return errs
+}
+
+// Returns the errors for the extra args and env of the components that set what the operator manages
+func validateExtras(instance *operatorv1alpha1.CertManager, ns string) field.ErrorList {
+	bundle, err := res.GetBundle(instance.Spec.Version)
+	if err != nil {
+		// Reported with the version
+		return nil
+	}
+	managed := instance.DeepCopy()
+	path := field.NewPath("spec")
+	components := []struct {
+		path      *field.Path
+		component *operatorv1alpha1.ComponentSpec
+		deploy    *appsv1.Deployment
+	}{
+		{path.Child("controller"), &managed.Spec.ControllerConfig, bundle.ControllerDeployment(ns)},
+		{path.Child("webhook"), &managed.Spec.WebhookConfig.ComponentSpec, bundle.WebhookDeployment(ns)},
+		{path.Child("cainjector"), &managed.Spec.CainjectorConfig, bundle.CainjectorDeployment(ns)},
+		{path.Child("configmapWatcher"), &managed.Spec.ConfigmapWatcherConfig, res.ConfigmapWatcherDeployment(ns)},
+	}
+
+	var errs field.ErrorList
+	for _, c := range components {
+		extraArgs, env := c.component.ExtraArgs, c.component.Env
+		// The operator's own args and env are those of the deployment without the extras
+		c.component.ExtraArgs, c.component.Env = nil, nil
+		deploy := setupDeploy(managed, bundle, c.deploy, ns)
+		container := deploy.Spec.Template.Spec.Containers[0]
+
+		_, conflicts := mergeArgs(container.Args, extraArgs)
+		for _, arg := range conflicts {
+			errs = append(errs, field.Forbidden(c.path.Child("extraArgs"), "flag "+flagName(arg)+" is set by the operator"))
+		}
+		_, conflicts = mergeEnv(container.Env, env)
+		for _, name := range conflicts {
+			errs = append(errs, field.Forbidden(c.path.Child("env"), "variable "+name+" is set by the operator"))
+		}
+	}
+	return errs
+}
+
+func validateImage(path *field.Path, image string) field.ErrorList {
+	if image == "" || imageRegexp.MatchString(image) {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(path, image, "must be an image reference, such as quay.io/opencloudio/image:tag or quay.io/opencloudio/image@sha256:digest")}
+}
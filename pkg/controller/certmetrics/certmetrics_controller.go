@@ -0,0 +1,105 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmetrics
+
+import (
+	"context"
+
+	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
+	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_certmetrics")
+
+// Add creates a new certificate metrics Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	dynclient, _ := dynamic.NewForConfig(mgr.GetConfig())
+	kubeclient, _ := kubernetes.NewForConfig(mgr.GetConfig())
+
+	return &ReconcileCertMetrics{
+		client:   mgr.GetClient(),
+		exporter: &exporter{dynclient: dynclient, kubeclient: kubeclient},
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	c, err := controller.New("certmetrics-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the CertManager, which enables the certificate metrics
+	err = c.Watch(&source.Kind{Type: &operatorv1alpha1.CertManager{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// blank assignment to verify that ReconcileCertMetrics implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileCertMetrics{}
+
+// ReconcileCertMetrics exports the metrics of the certificates when they are enabled on the CertManager.
+// The certificates are watched with the dynamic client, since their CRDs are created by the operator
+// and are in the API group of the deployed cert-manager release.
+type ReconcileCertMetrics struct {
+	client   client.Client
+	exporter *exporter
+}
+
+// Reconcile starts exporting the metrics of the certificates of the CertManager's cert-manager release,
+// and stops when they are disabled or the CertManager is deleted
+func (r *ReconcileCertMetrics) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	if request.Name != "default" {
+		return reconcile.Result{}, nil
+	}
+	instance := &operatorv1alpha1.CertManager{}
+	if err := r.client.Get(context.TODO(), request.NamespacedName, instance); err != nil {
+		if apiErrors.IsNotFound(err) {
+			r.exporter.shutdown()
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	bundle, err := res.GetBundle(instance.Spec.Version)
+	if err != nil || !instance.ObjectMeta.DeletionTimestamp.IsZero() || !instance.Spec.Metrics.Certificates {
+		r.exporter.shutdown()
+		return reconcile.Result{}, nil
+	}
+
+	r.exporter.run(schema.GroupVersionResource{Group: bundle.Group, Version: bundle.APIVersion, Resource: "certificates"})
+	return reconcile.Result{}, nil
+}
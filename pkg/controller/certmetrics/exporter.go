@@ -0,0 +1,306 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmetrics
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"sync"
+	"time"
+
+	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// How often every certificate's metrics are refreshed. They are also refreshed when a certificate or its secret
+// changes, which catches a renewed secret even when cert-manager didn't change the certificate.
+const resyncPeriod = 10 * time.Minute
+
+// The name of the index of the certificates by the namespace/name of their secret
+const secretIndex = "secret"
+
+// How long before a legacy certificate expires cert-manager 0.10.3 renews it, unless it sets renewBefore
+const legacyRenewBefore = 30 * 24 * time.Hour
+
+// How long a cert-manager.io certificate is valid for, unless it sets duration. It is
+// renewed after two thirds of it, unless it sets renewBefore.
+const v1Duration = 90 * 24 * time.Hour
+
+// exporter watches the certificates of a cert-manager release and their secrets, and keeps the certificates'
+// metrics up to date
+type exporter struct {
+	dynclient  dynamic.Interface
+	kubeclient kubernetes.Interface
+
+	mu   sync.Mutex
+	gvr  schema.GroupVersionResource
+	stop chan struct{}
+	// certificates are the certificates metrics are exported for, by namespace/name
+	certificates map[string]*certificateState
+}
+
+// certificateState is what is remembered of a certificate to update its metrics
+type certificateState struct {
+	issuerName, issuerKind string
+	lastFailureTime        string
+}
+
+// Watches the certificates of the resource, restarting the watch if it was on another resource
+func (e *exporter) run(gvr schema.GroupVersionResource) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stop != nil && e.gvr == gvr {
+		return
+	}
+	e.stopLocked()
+
+	log.Info("Exporting certificate metrics", "resource", gvr.String())
+	e.gvr = gvr
+	e.stop = make(chan struct{})
+	e.certificates = make(map[string]*certificateState)
+
+	// The secrets are read from the cache of their informer, which is stopped with the certificates' informer
+	secretInformer := informers.NewSharedInformerFactory(e.kubeclient, 0).Core().V1().Secrets()
+	secrets := secretInformer.Lister()
+	certInformer := dynamicinformer.NewDynamicSharedInformerFactory(e.dynclient, resyncPeriod).ForResource(gvr).Informer()
+	if err := certInformer.AddIndexers(cache.Indexers{secretIndex: certificateSecretKey}); err != nil {
+		log.Error(err, "Error indexing the certificates by their secret")
+	}
+	certInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { e.update(secrets, obj) },
+		UpdateFunc: func(_, obj interface{}) { e.update(secrets, obj) },
+		DeleteFunc: e.delete,
+	})
+	updateSecret := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		secret, ok := obj.(*corev1.Secret)
+		if !ok {
+			return
+		}
+		certs, _ := certInformer.GetIndexer().ByIndex(secretIndex, secret.Namespace+"/"+secret.Name)
+		for _, cert := range certs {
+			e.update(secrets, cert)
+		}
+	}
+	secretInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    updateSecret,
+		UpdateFunc: func(_, obj interface{}) { updateSecret(obj) },
+		DeleteFunc: updateSecret,
+	})
+
+	stop := e.stop
+	go secretInformer.Informer().Run(stop)
+	go func() {
+		// The certificates are watched once the secrets are cached, so that their expiry is read from the start
+		if cache.WaitForCacheSync(stop, secretInformer.Informer().HasSynced) {
+			certInformer.Run(stop)
+		}
+	}()
+}
+
+// Stops watching the certificates and removes their metrics
+func (e *exporter) shutdown() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stopLocked()
+}
+
+func (e *exporter) stopLocked() {
+	if e.stop == nil {
+		return
+	}
+	log.Info("No longer exporting certificate metrics", "resource", e.gvr.String())
+	close(e.stop)
+	e.stop = nil
+	e.certificates = nil
+	resetMetrics()
+}
+
+func (e *exporter) update(secrets corelisters.SecretLister, obj interface{}) {
+	cert, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.certificates == nil {
+		// The exporter was stopped after the event was queued
+		return
+	}
+	ns, name := cert.GetNamespace(), cert.GetName()
+	key := ns + "/" + name
+
+	notAfter := notAfter(secrets, cert)
+	if notAfter.IsZero() {
+		certificateExpiry.DeleteLabelValues(ns, name)
+		certificateRenewal.DeleteLabelValues(ns, name)
+	} else {
+		certificateExpiry.WithLabelValues(ns, name).Set(float64(notAfter.Unix()))
+		certificateRenewal.WithLabelValues(ns, name).Set(float64(renewalTime(cert, notAfter, e.gvr.Group == res.GroupVersion).Unix()))
+	}
+
+	ready := readyStatus(cert)
+	for _, condition := range readyConditions {
+		value := 0.0
+		if condition == ready {
+			value = 1
+		}
+		certificateReady.WithLabelValues(ns, name, condition).Set(value)
+	}
+
+	issuerName, _, _ := unstructured.NestedString(cert.Object, "spec", "issuerRef", "name")
+	issuerKind, _, _ := unstructured.NestedString(cert.Object, "spec", "issuerRef", "kind")
+	if issuerKind == "" {
+		issuerKind = "Issuer"
+	}
+	lastFailureTime, _, _ := unstructured.NestedString(cert.Object, "status", "lastFailureTime")
+	state, seen := e.certificates[key]
+	if !seen {
+		// Failures from before the certificate was first seen aren't counted, the counter starts at 0
+		state = &certificateState{lastFailureTime: lastFailureTime}
+		e.certificates[key] = state
+		certificateIssuanceFailures.WithLabelValues(ns, name)
+	} else if lastFailureTime != "" && lastFailureTime != state.lastFailureTime {
+		certificateIssuanceFailures.WithLabelValues(ns, name).Inc()
+		state.lastFailureTime = lastFailureTime
+	}
+	if state.issuerName != issuerName || state.issuerKind != issuerKind {
+		certificateInfo.DeleteLabelValues(ns, name, state.issuerName, state.issuerKind)
+		state.issuerName, state.issuerKind = issuerName, issuerKind
+	}
+	certificateInfo.WithLabelValues(ns, name, issuerName, issuerKind).Set(1)
+}
+
+func (e *exporter) delete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	cert, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ns, name := cert.GetNamespace(), cert.GetName()
+	key := ns + "/" + name
+	state, seen := e.certificates[key]
+	if !seen {
+		return
+	}
+	delete(e.certificates, key)
+	certificateExpiry.DeleteLabelValues(ns, name)
+	certificateRenewal.DeleteLabelValues(ns, name)
+	for _, condition := range readyConditions {
+		certificateReady.DeleteLabelValues(ns, name, condition)
+	}
+	certificateInfo.DeleteLabelValues(ns, name, state.issuerName, state.issuerKind)
+	certificateIssuanceFailures.DeleteLabelValues(ns, name)
+}
+
+// Indexes a certificate by the namespace/name of its secret
+func certificateSecretKey(obj interface{}) ([]string, error) {
+	cert, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, nil
+	}
+	secretName, _, _ := unstructured.NestedString(cert.Object, "spec", "secretName")
+	if secretName == "" {
+		return nil, nil
+	}
+	return []string{cert.GetNamespace() + "/" + secretName}, nil
+}
+
+// Returns the status of the certificate's Ready condition, Unknown when it has none
+func readyStatus(cert *unstructured.Unstructured) string {
+	ready := string(corev1.ConditionUnknown)
+	conditions, _, _ := unstructured.NestedSlice(cert.Object, "status", "conditions")
+	for _, item := range conditions {
+		if condition, ok := item.(map[string]interface{}); ok && condition["type"] == "Ready" {
+			if status, ok := condition["status"].(string); ok {
+				ready = status
+			}
+		}
+	}
+	return ready
+}
+
+// Returns when the certificate expires, read from the certificate in its cached secret. The certificate's
+// status is used when the secret can't be read, and the zero time when neither has it.
+func notAfter(secrets corelisters.SecretLister, cert *unstructured.Unstructured) time.Time {
+	secretName, _, _ := unstructured.NestedString(cert.Object, "spec", "secretName")
+	if secretName != "" {
+		secret, err := secrets.Secrets(cert.GetNamespace()).Get(secretName)
+		if err == nil {
+			if block, _ := pem.Decode(secret.Data[corev1.TLSCertKey]); block != nil {
+				if parsed, err := x509.ParseCertificate(block.Bytes); err == nil {
+					return parsed.NotAfter
+				}
+			}
+		} else {
+			log.V(2).Info("Error reading the secret of the certificate", "namespace", cert.GetNamespace(), "name", cert.GetName(), "error", err)
+		}
+	}
+	return statusTime(cert, "notAfter")
+}
+
+// Returns when cert-manager renews the certificate. cert-manager.io certificates record it in their
+// status once issued, otherwise it is worked out the way the deployed cert-manager release does.
+func renewalTime(cert *unstructured.Unstructured, notAfter time.Time, legacy bool) time.Time {
+	if renewal := statusTime(cert, "renewalTime"); !renewal.IsZero() {
+		return renewal
+	}
+	renewBefore := specDuration(cert, "renewBefore")
+	if renewBefore == 0 && legacy {
+		renewBefore = legacyRenewBefore
+	} else if renewBefore == 0 {
+		duration := specDuration(cert, "duration")
+		if duration == 0 {
+			duration = v1Duration
+		}
+		renewBefore = duration / 3
+	}
+	return notAfter.Add(-renewBefore)
+}
+
+func statusTime(cert *unstructured.Unstructured, field string) time.Time {
+	value, _, _ := unstructured.NestedString(cert.Object, "status", field)
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return parsed
+}
+
+func specDuration(cert *unstructured.Unstructured, field string) time.Duration {
+	value, _, _ := unstructured.NestedString(cert.Object, "spec", field)
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0
+	}
+	return parsed
+}
@@ -0,0 +1,254 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmetrics
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Returns a certificate with the spec and status fields
+func newCertificate(spec, status map[string]interface{}) *unstructured.Unstructured {
+	cert := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec, "status": status}}
+	cert.SetNamespace("test")
+	cert.SetName("cert")
+	return cert
+}
+
+// Returns a lister of the secrets
+func newSecretLister(t *testing.T, secrets ...*corev1.Secret) corelisters.SecretLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, secret := range secrets {
+		if err := indexer.Add(secret); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return corelisters.NewSecretLister(indexer)
+}
+
+// Returns a PEM encoded self-signed certificate that expires at notAfter
+func newCertPEM(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: notAfter.Add(-time.Hour), NotAfter: notAfter}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestRenewalTime(t *testing.T) {
+	notAfter := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		spec   map[string]interface{}
+		status map[string]interface{}
+		legacy bool
+		want   time.Time
+	}{
+		{
+			name:   "renewal time in the status",
+			spec:   map[string]interface{}{"renewBefore": "1h"},
+			status: map[string]interface{}{"renewalTime": "2021-02-01T00:00:00Z"},
+			want:   time.Date(2021, time.February, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "legacy default",
+			legacy: true,
+			want:   notAfter.Add(-30 * 24 * time.Hour),
+		},
+		{
+			name:   "legacy renew before",
+			spec:   map[string]interface{}{"renewBefore": "240h"},
+			legacy: true,
+			want:   notAfter.Add(-240 * time.Hour),
+		},
+		{
+			name: "v1 default",
+			want: notAfter.Add(-30 * 24 * time.Hour),
+		},
+		{
+			name: "v1 duration",
+			spec: map[string]interface{}{"duration": "2160h"},
+			want: notAfter.Add(-720 * time.Hour),
+		},
+		{
+			name: "v1 renew before",
+			spec: map[string]interface{}{"duration": "2160h", "renewBefore": "24h"},
+			want: notAfter.Add(-24 * time.Hour),
+		},
+		{
+			name:   "invalid renewal time",
+			status: map[string]interface{}{"renewalTime": "soon"},
+			legacy: true,
+			want:   notAfter.Add(-30 * 24 * time.Hour),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := renewalTime(newCertificate(test.spec, test.status), notAfter, test.legacy); !got.Equal(test.want) {
+				t.Errorf("renewalTime() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReadyStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []interface{}
+		want       string
+	}{
+		{
+			name: "no conditions",
+			want: "Unknown",
+		},
+		{
+			name:       "ready",
+			conditions: []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+			want:       "True",
+		},
+		{
+			name: "not ready",
+			conditions: []interface{}{
+				map[string]interface{}{"type": "Issuing", "status": "True"},
+				map[string]interface{}{"type": "Ready", "status": "False"},
+			},
+			want: "False",
+		},
+		{
+			name:       "other condition only",
+			conditions: []interface{}{map[string]interface{}{"type": "Issuing", "status": "True"}},
+			want:       "Unknown",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := map[string]interface{}{}
+			if test.conditions != nil {
+				status["conditions"] = test.conditions
+			}
+			if got := readyStatus(newCertificate(nil, status)); got != test.want {
+				t.Errorf("readyStatus() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNotAfter(t *testing.T) {
+	expiry := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "tls"},
+		Data:       map[string][]byte{corev1.TLSCertKey: newCertPEM(t, expiry)},
+	}
+	tests := []struct {
+		name   string
+		spec   map[string]interface{}
+		status map[string]interface{}
+		want   time.Time
+	}{
+		{
+			name: "read from the secret",
+			spec: map[string]interface{}{"secretName": "tls"},
+			// The secret is preferred to the status, which may not have been updated yet
+			status: map[string]interface{}{"notAfter": "2020-01-01T00:00:00Z"},
+			want:   expiry,
+		},
+		{
+			name:   "secret not found",
+			spec:   map[string]interface{}{"secretName": "missing"},
+			status: map[string]interface{}{"notAfter": "2020-01-01T00:00:00Z"},
+			want:   time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "neither",
+			spec: map[string]interface{}{"secretName": "missing"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := notAfter(newSecretLister(t, secret), newCertificate(test.spec, test.status)); !got.Equal(test.want) {
+				t.Errorf("notAfter() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIssuanceFailures(t *testing.T) {
+	tests := []struct {
+		name            string
+		lastFailureTime string
+		want            float64
+	}{
+		{
+			name:            "failure from before the certificate was seen",
+			lastFailureTime: "2021-01-01T00:00:00Z",
+			want:            0,
+		},
+		{
+			name:            "same failure",
+			lastFailureTime: "2021-01-01T00:00:00Z",
+			want:            0,
+		},
+		{
+			name:            "new failure",
+			lastFailureTime: "2021-01-02T00:00:00Z",
+			want:            1,
+		},
+		{
+			name: "issued",
+			want: 1,
+		},
+		{
+			name:            "another failure",
+			lastFailureTime: "2021-01-03T00:00:00Z",
+			want:            2,
+		},
+	}
+	defer resetMetrics()
+	e := &exporter{certificates: make(map[string]*certificateState)}
+	secrets := newSecretLister(t)
+	// The steps update the same certificate in turn
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := map[string]interface{}{}
+			if test.lastFailureTime != "" {
+				status["lastFailureTime"] = test.lastFailureTime
+			}
+			e.update(secrets, newCertificate(map[string]interface{}{"issuerRef": map[string]interface{}{"name": "ca"}}, status))
+			if got := testutil.ToFloat64(certificateIssuanceFailures.WithLabelValues("test", "cert")); got != test.want {
+				t.Errorf("issuance failures = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certmetrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// The metrics are prefixed so that they aren't mistaken for the ones cert-manager-controller exports itself
+const metricsPrefix = "certmanager_operator_certificate_"
+
+// readyConditions are the values a certificate's Ready condition is reported with
+var readyConditions = []string{"True", "False", "Unknown"}
+
+var (
+	certificateExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: metricsPrefix + "expiration_timestamp_seconds",
+		Help: "The time the certificate expires, read from its secret",
+	}, []string{"namespace", "name"})
+
+	certificateRenewal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: metricsPrefix + "renewal_timestamp_seconds",
+		Help: "The time cert-manager renews the certificate at",
+	}, []string{"namespace", "name"})
+
+	certificateReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: metricsPrefix + "ready_status",
+		Help: "The status of the certificate's Ready condition, 1 for the condition it is in",
+	}, []string{"namespace", "name", "condition"})
+
+	certificateInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: metricsPrefix + "info",
+		Help: "The issuer of the certificate, always 1",
+	}, []string{"namespace", "name", "issuer_name", "issuer_kind"})
+
+	certificateIssuanceFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: metricsPrefix + "issuance_failures_total",
+		Help: "The number of failed issuances of the certificate seen since the operator started",
+	}, []string{"namespace", "name"})
+)
+
+func init() {
+	// The controller-runtime registry is served from the operator's metrics endpoint
+	metrics.Registry.MustRegister(certificateExpiry, certificateRenewal, certificateReady, certificateInfo, certificateIssuanceFailures)
+}
+
+// Removes every certificate's metrics
+func resetMetrics() {
+	certificateExpiry.Reset()
+	certificateRenewal.Reset()
+	certificateReady.Reset()
+	certificateInfo.Reset()
+	certificateIssuanceFailures.Reset()
+}
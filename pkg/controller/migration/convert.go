@@ -0,0 +1,206 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package migration
+
+import (
+	"fmt"
+	"strings"
+
+	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// migratedFromAnnotation marks the objects created by the migration with the API they were converted from
+const migratedFromAnnotation = "operator.ibm.com/migrated-from"
+
+const legacyAnnotationPrefix = res.GroupVersion + "/"
+const v1AnnotationPrefix = res.V1Group + "/"
+
+// Converts a legacy certificate, issuer or clusterissuer into its cert-manager.io/v1 equivalent.
+// Returns notes about the parts of the object that changed meaning, or an error if the object
+// uses configuration that has no equivalent and must be migrated by hand.
+func convert(legacy *unstructured.Unstructured) (*unstructured.Unstructured, []string, error) {
+	spec, _, err := unstructured.NestedMap(legacy.Object, "spec")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var converted map[string]interface{}
+	var notes []string
+	switch legacy.GetKind() {
+	case "Certificate":
+		converted, notes, err = convertCertificateSpec(spec)
+	case "Issuer", "ClusterIssuer":
+		converted, notes, err = convertIssuerSpec(spec)
+	default:
+		err = fmt.Errorf("kind %s can't be migrated", legacy.GetKind())
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": converted}}
+	obj.SetAPIVersion(res.V1Group + "/v1")
+	obj.SetKind(legacy.GetKind())
+	obj.SetName(legacy.GetName())
+	obj.SetNamespace(legacy.GetNamespace())
+	obj.SetLabels(legacy.GetLabels())
+	annotations := convertAnnotations(legacy.GetAnnotations())
+	annotations[migratedFromAnnotation] = legacy.GetAPIVersion()
+	obj.SetAnnotations(annotations)
+	return obj, notes, nil
+}
+
+// Returns a copy of the annotations with the legacy certmanager.k8s.io prefix replaced by cert-manager.io
+func convertAnnotations(annotations map[string]string) map[string]string {
+	converted := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		if k == "kubectl.kubernetes.io/last-applied-configuration" {
+			continue
+		}
+		converted[strings.Replace(k, legacyAnnotationPrefix, v1AnnotationPrefix, 1)] = v
+	}
+	return converted
+}
+
+func convertCertificateSpec(spec map[string]interface{}) (map[string]interface{}, []string, error) {
+	converted := make(map[string]interface{})
+	privateKey := make(map[string]interface{})
+	var notes []string
+	for field, value := range spec {
+		switch field {
+		case "secretName", "commonName", "dnsNames", "ipAddresses", "duration", "renewBefore", "isCA", "usages":
+			converted[field] = value
+		case "organization":
+			converted["subject"] = map[string]interface{}{"organizations": value}
+		case "keyAlgorithm":
+			privateKey["algorithm"] = strings.ToUpper(fmt.Sprint(value))
+		case "keyEncoding":
+			privateKey["encoding"] = strings.ToUpper(fmt.Sprint(value))
+		case "keySize":
+			privateKey["size"] = value
+		case "issuerRef":
+			ref, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf("spec.issuerRef is not an object")
+			}
+			converted[field] = convertIssuerRef(ref)
+		case "acme":
+			// The solvers are chosen by the issuer in cert-manager.io/v1
+			notes = append(notes, "spec.acme was dropped, the issuer's solvers are used instead")
+		default:
+			return nil, nil, fmt.Errorf("spec.%s has no cert-manager.io/v1 equivalent", field)
+		}
+	}
+	if len(privateKey) > 0 {
+		converted["privateKey"] = privateKey
+	}
+	return converted, notes, nil
+}
+
+// Returns a copy of the issuer reference pointing at the cert-manager.io group if it pointed at the legacy group
+func convertIssuerRef(ref map[string]interface{}) map[string]interface{} {
+	converted := make(map[string]interface{}, len(ref))
+	for k, v := range ref {
+		converted[k] = v
+	}
+	if converted["group"] == res.GroupVersion {
+		converted["group"] = res.V1Group
+	}
+	return converted
+}
+
+func convertIssuerSpec(spec map[string]interface{}) (map[string]interface{}, []string, error) {
+	converted := make(map[string]interface{})
+	var notes []string
+	for field, value := range spec {
+		switch field {
+		case "ca", "selfSigned", "vault", "venafi":
+			converted[field] = value
+		case "acme":
+			acme, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf("spec.acme is not an object")
+			}
+			convertedACME, acmeNotes, err := convertACMEIssuer(acme)
+			if err != nil {
+				return nil, nil, err
+			}
+			converted[field] = convertedACME
+			notes = append(notes, acmeNotes...)
+		default:
+			return nil, nil, fmt.Errorf("spec.%s has no cert-manager.io/v1 equivalent", field)
+		}
+	}
+	return converted, notes, nil
+}
+
+// Converts the ACME issuer configuration. The deprecated http01 and dns01 settings, which the
+// certificates used to choose between, are turned into a solver when there is only one to choose.
+func convertACMEIssuer(acme map[string]interface{}) (map[string]interface{}, []string, error) {
+	converted := make(map[string]interface{})
+	var legacySolvers []interface{}
+	var notes []string
+	for field, value := range acme {
+		switch field {
+		case "email", "server", "skipTLSVerify", "privateKeySecretRef", "solvers":
+			converted[field] = value
+		case "http01":
+			ingress := make(map[string]interface{})
+			if http01, ok := value.(map[string]interface{}); ok && http01["serviceType"] != nil {
+				ingress["serviceType"] = http01["serviceType"]
+			}
+			legacySolvers = append(legacySolvers, map[string]interface{}{"http01": map[string]interface{}{"ingress": ingress}})
+		case "dns01":
+			providers, _, err := unstructured.NestedSlice(acme, "dns01", "providers")
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, item := range providers {
+				provider, ok := item.(map[string]interface{})
+				if !ok {
+					return nil, nil, fmt.Errorf("spec.acme.dns01.providers contains an item that is not an object")
+				}
+				solver := make(map[string]interface{})
+				for k, v := range provider {
+					if k != "name" {
+						solver[k] = v
+					}
+				}
+				legacySolvers = append(legacySolvers, map[string]interface{}{"dns01": solver})
+			}
+		default:
+			return nil, nil, fmt.Errorf("spec.acme.%s has no cert-manager.io/v1 equivalent", field)
+		}
+	}
+
+	if len(legacySolvers) == 0 {
+		return converted, notes, nil
+	}
+	if converted["solvers"] != nil {
+		notes = append(notes, "the deprecated spec.acme.http01 and spec.acme.dns01 settings were dropped in favour of spec.acme.solvers")
+		return converted, notes, nil
+	}
+	if len(legacySolvers) > 1 {
+		return nil, nil, fmt.Errorf("spec.acme configures %d http01 and dns01 providers, which certificates chose between. "+
+			"Configure spec.acme.solvers with selectors to migrate the issuer", len(legacySolvers))
+	}
+	converted["solvers"] = legacySolvers
+	notes = append(notes, "the deprecated spec.acme solver settings were converted to spec.acme.solvers")
+	return converted, notes, nil
+}
@@ -0,0 +1,217 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package migration
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConvertCertificateSpec(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      map[string]interface{}
+		want      map[string]interface{}
+		wantNotes []string
+		wantErr   bool
+	}{
+		{
+			name: "fields kept as they are",
+			spec: map[string]interface{}{
+				"secretName": "tls", "commonName": "example.com", "dnsNames": []interface{}{"example.com"},
+				"duration": "2160h", "renewBefore": "360h", "isCA": false,
+			},
+			want: map[string]interface{}{
+				"secretName": "tls", "commonName": "example.com", "dnsNames": []interface{}{"example.com"},
+				"duration": "2160h", "renewBefore": "360h", "isCA": false,
+			},
+		},
+		{
+			name: "organization and key settings",
+			spec: map[string]interface{}{
+				"secretName": "tls", "organization": []interface{}{"IBM"},
+				"keyAlgorithm": "ecdsa", "keyEncoding": "pkcs8", "keySize": int64(256),
+			},
+			want: map[string]interface{}{
+				"secretName": "tls",
+				"subject":    map[string]interface{}{"organizations": []interface{}{"IBM"}},
+				"privateKey": map[string]interface{}{"algorithm": "ECDSA", "encoding": "PKCS8", "size": int64(256)},
+			},
+		},
+		{
+			name: "issuer reference to the legacy group",
+			spec: map[string]interface{}{
+				"secretName": "tls",
+				"issuerRef":  map[string]interface{}{"name": "ca", "kind": "ClusterIssuer", "group": "certmanager.k8s.io"},
+			},
+			want: map[string]interface{}{
+				"secretName": "tls",
+				"issuerRef":  map[string]interface{}{"name": "ca", "kind": "ClusterIssuer", "group": "cert-manager.io"},
+			},
+		},
+		{
+			name: "issuer reference to an external issuer",
+			spec: map[string]interface{}{
+				"issuerRef": map[string]interface{}{"name": "pca", "kind": "AWSPCAIssuer", "group": "awspca.cert-manager.io"},
+			},
+			want: map[string]interface{}{
+				"issuerRef": map[string]interface{}{"name": "pca", "kind": "AWSPCAIssuer", "group": "awspca.cert-manager.io"},
+			},
+		},
+		{
+			name: "acme solver configuration",
+			spec: map[string]interface{}{
+				"secretName": "tls",
+				"acme":       map[string]interface{}{"config": []interface{}{map[string]interface{}{"http01": map[string]interface{}{}}}},
+			},
+			want:      map[string]interface{}{"secretName": "tls"},
+			wantNotes: []string{"spec.acme was dropped, the issuer's solvers are used instead"},
+		},
+		{
+			name:    "issuer reference that isn't an object",
+			spec:    map[string]interface{}{"issuerRef": "ca"},
+			wantErr: true,
+		},
+		{
+			name:    "field without an equivalent",
+			spec:    map[string]interface{}{"secretName": "tls", "unknown": true},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, notes, err := convertCertificateSpec(test.spec)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("convertCertificateSpec() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("convertCertificateSpec() = %v, want %v", got, test.want)
+			}
+			if !reflect.DeepEqual(notes, test.wantNotes) {
+				t.Errorf("convertCertificateSpec() notes = %v, want %v", notes, test.wantNotes)
+			}
+		})
+	}
+}
+
+func TestConvertACMEIssuer(t *testing.T) {
+	account := map[string]interface{}{
+		"email":               "admin@example.com",
+		"server":              "https://acme-staging-v02.api.letsencrypt.org/directory",
+		"privateKeySecretRef": map[string]interface{}{"name": "acme-account"},
+	}
+	with := func(fields map[string]interface{}) map[string]interface{} {
+		merged := make(map[string]interface{}, len(account)+len(fields))
+		for k, v := range account {
+			merged[k] = v
+		}
+		for k, v := range fields {
+			merged[k] = v
+		}
+		return merged
+	}
+	cloudflare := map[string]interface{}{"email": "admin@example.com", "apiKeySecretRef": map[string]interface{}{"name": "cloudflare", "key": "api-key"}}
+	solvers := []interface{}{map[string]interface{}{"http01": map[string]interface{}{"ingress": map[string]interface{}{"class": "nginx"}}}}
+
+	tests := []struct {
+		name      string
+		acme      map[string]interface{}
+		want      map[string]interface{}
+		wantNotes []string
+		wantErr   bool
+	}{
+		{
+			name: "solvers",
+			acme: with(map[string]interface{}{"solvers": solvers}),
+			want: with(map[string]interface{}{"solvers": solvers}),
+		},
+		{
+			name: "http01",
+			acme: with(map[string]interface{}{"http01": map[string]interface{}{"serviceType": "ClusterIP"}}),
+			want: with(map[string]interface{}{"solvers": []interface{}{
+				map[string]interface{}{"http01": map[string]interface{}{"ingress": map[string]interface{}{"serviceType": "ClusterIP"}}},
+			}}),
+			wantNotes: []string{"the deprecated spec.acme solver settings were converted to spec.acme.solvers"},
+		},
+		{
+			name: "http01 without settings",
+			acme: with(map[string]interface{}{"http01": map[string]interface{}{}}),
+			want: with(map[string]interface{}{"solvers": []interface{}{
+				map[string]interface{}{"http01": map[string]interface{}{"ingress": map[string]interface{}{}}},
+			}}),
+			wantNotes: []string{"the deprecated spec.acme solver settings were converted to spec.acme.solvers"},
+		},
+		{
+			name: "single dns01 provider",
+			acme: with(map[string]interface{}{"dns01": map[string]interface{}{"providers": []interface{}{
+				map[string]interface{}{"name": "cf", "cloudflare": cloudflare},
+			}}}),
+			want: with(map[string]interface{}{"solvers": []interface{}{
+				map[string]interface{}{"dns01": map[string]interface{}{"cloudflare": cloudflare}},
+			}}),
+			wantNotes: []string{"the deprecated spec.acme solver settings were converted to spec.acme.solvers"},
+		},
+		{
+			name: "deprecated settings alongside solvers",
+			acme: with(map[string]interface{}{"solvers": solvers, "http01": map[string]interface{}{}}),
+			want: with(map[string]interface{}{"solvers": solvers}),
+			wantNotes: []string{
+				"the deprecated spec.acme.http01 and spec.acme.dns01 settings were dropped in favour of spec.acme.solvers",
+			},
+		},
+		{
+			name: "http01 and dns01 to choose between",
+			acme: with(map[string]interface{}{
+				"http01": map[string]interface{}{},
+				"dns01":  map[string]interface{}{"providers": []interface{}{map[string]interface{}{"name": "cf", "cloudflare": cloudflare}}},
+			}),
+			wantErr: true,
+		},
+		{
+			name: "several dns01 providers to choose between",
+			acme: with(map[string]interface{}{"dns01": map[string]interface{}{"providers": []interface{}{
+				map[string]interface{}{"name": "cf", "cloudflare": cloudflare},
+				map[string]interface{}{"name": "cf2", "cloudflare": cloudflare},
+			}}}),
+			wantErr: true,
+		},
+		{
+			name:    "dns01 provider that isn't an object",
+			acme:    with(map[string]interface{}{"dns01": map[string]interface{}{"providers": []interface{}{"cf"}}}),
+			wantErr: true,
+		},
+		{
+			name:    "field without an equivalent",
+			acme:    with(map[string]interface{}{"unknown": true}),
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, notes, err := convertACMEIssuer(test.acme)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("convertACMEIssuer() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("convertACMEIssuer() = %v, want %v", got, test.want)
+			}
+			if !reflect.DeepEqual(notes, test.wantNotes) {
+				t.Errorf("convertACMEIssuer() notes = %v, want %v", notes, test.wantNotes)
+			}
+		})
+	}
+}
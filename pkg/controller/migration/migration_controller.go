@@ -0,0 +1,305 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package migration
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	operatorv1alpha1 "github.com/ibm/ibm-cert-manager-operator/pkg/apis/operator/v1alpha1"
+	res "github.com/ibm/ibm-cert-manager-operator/pkg/resources"
+
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_migration")
+
+// retryInterval is how long to wait before retrying the objects that failed to migrate
+const retryInterval = time.Minute
+
+// maxReportedObjects caps the objects listed in the status, so that a cluster with many legacy
+// objects doesn't push the CertManager past the size limit of an object
+const maxReportedObjects = 100
+
+// reportOrder is the order the objects are listed in the status, failures first
+var reportOrder = map[operatorv1alpha1.MigrationState]int{
+	operatorv1alpha1.MigrationFailed:       0,
+	operatorv1alpha1.MigrationSkipped:      1,
+	operatorv1alpha1.MigrationWouldMigrate: 2,
+}
+
+// migratedKinds are the legacy kinds that are migrated, in order. The issuers are
+// migrated before the certificates so that the certificates' issuers exist.
+var migratedKinds = []struct {
+	kind     string
+	resource string
+}{
+	{kind: "ClusterIssuer", resource: "clusterissuers"},
+	{kind: "Issuer", resource: "issuers"},
+	{kind: "Certificate", resource: "certificates"},
+}
+
+// Add creates a new migration Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	dynclient, _ := dynamic.NewForConfig(mgr.GetConfig())
+	kubeclient, _ := kubernetes.NewForConfig(mgr.GetConfig())
+
+	return &ReconcileMigration{
+		client:     mgr.GetClient(),
+		dynclient:  dynclient,
+		kubeclient: kubeclient,
+		recorder:   mgr.GetEventRecorderFor("ibm-cert-manager-operator"),
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	c, err := controller.New("migration-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the CertManager, which enables the migration
+	err = c.Watch(&source.Kind{Type: &operatorv1alpha1.CertManager{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// blank assignment to verify that ReconcileMigration implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileMigration{}
+
+// ReconcileMigration migrates the certmanager.k8s.io resources to cert-manager.io when it is enabled on the CertManager.
+// The legacy resources are read with the dynamic client, since their CRDs may not be installed.
+type ReconcileMigration struct {
+	client     client.Client
+	dynclient  dynamic.Interface
+	kubeclient kubernetes.Interface
+	recorder   record.EventRecorder
+}
+
+// Reconcile converts every legacy certificate, issuer and clusterissuer that hasn't been migrated yet and
+// records the outcome for each of them in the CertManager's status
+func (r *ReconcileMigration) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	instance := &operatorv1alpha1.CertManager{}
+	if err := r.client.Get(context.TODO(), request.NamespacedName, instance); err != nil {
+		if apiErrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if request.Name != "default" || !instance.ObjectMeta.DeletionTimestamp.IsZero() || !instance.Spec.Migration.Enabled || instance.Paused() {
+		return reconcile.Result{}, nil
+	}
+
+	dryRun := instance.Spec.Migration.DryRun
+	log.Info("Migrating certmanager.k8s.io resources", "dry run", dryRun)
+	status := &operatorv1alpha1.MigrationStatus{DryRun: dryRun}
+	for _, k := range migratedKinds {
+		legacyGVR := schema.GroupVersionResource{Group: res.GroupVersion, Version: res.CRDVersion, Resource: k.resource}
+		list, err := r.dynclient.Resource(legacyGVR).List(metav1.ListOptions{})
+		if err != nil {
+			if apiErrors.IsNotFound(err) {
+				log.V(2).Info("Legacy CRD not installed, nothing to migrate", "resource", k.resource)
+				continue
+			}
+			return reconcile.Result{}, err
+		}
+		for i := range list.Items {
+			objStatus := r.migrate(&list.Items[i], k.resource, dryRun)
+			switch objStatus.State {
+			case operatorv1alpha1.MigrationMigrated:
+				// Only counted, the migrated objects can be listed with the migrated-from annotation
+				status.Migrated++
+				continue
+			case operatorv1alpha1.MigrationWouldMigrate:
+				status.Migrated++
+			case operatorv1alpha1.MigrationSkipped:
+				status.Skipped++
+			case operatorv1alpha1.MigrationFailed:
+				status.Failed++
+			}
+			status.Objects = append(status.Objects, objStatus)
+		}
+	}
+	sort.SliceStable(status.Objects, func(i, j int) bool {
+		return reportOrder[status.Objects[i].State] < reportOrder[status.Objects[j].State]
+	})
+	if len(status.Objects) > maxReportedObjects {
+		status.Objects = status.Objects[:maxReportedObjects]
+	}
+
+	if !reflect.DeepEqual(instance.Status.Migration, status) {
+		instance.Status.Migration = status
+		if err := r.client.Status().Update(context.TODO(), instance); err != nil {
+			return reconcile.Result{}, err
+		}
+		msg := fmt.Sprintf("Migrated %d certmanager.k8s.io resources, %d failed, %d skipped", status.Migrated, status.Failed, status.Skipped)
+		if dryRun {
+			msg = fmt.Sprintf("Dry run: %d certmanager.k8s.io resources would be migrated, %d would fail, %d would be skipped",
+				status.Migrated, status.Failed, status.Skipped)
+		}
+		r.recorder.Event(instance, corev1.EventTypeNormal, "Migration", msg)
+	}
+	log.Info("Finished migrating certmanager.k8s.io resources", "migrated", status.Migrated, "failed", status.Failed, "skipped", status.Skipped)
+
+	if status.Failed > 0 && !dryRun {
+		return reconcile.Result{RequeueAfter: retryInterval}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// Migrates a single legacy object, only reporting what would be done if this is a dry run
+func (r *ReconcileMigration) migrate(legacy *unstructured.Unstructured, resource string, dryRun bool) operatorv1alpha1.MigrationObjectStatus {
+	objStatus := operatorv1alpha1.MigrationObjectStatus{
+		Kind:      legacy.GetKind(),
+		Namespace: legacy.GetNamespace(),
+		Name:      legacy.GetName(),
+	}
+	failed := func(err error) operatorv1alpha1.MigrationObjectStatus {
+		objStatus.State = operatorv1alpha1.MigrationFailed
+		objStatus.Message = err.Error()
+		return objStatus
+	}
+
+	converted, notes, err := convert(legacy)
+	if err != nil {
+		return failed(err)
+	}
+
+	v1GVR := schema.GroupVersionResource{Group: res.V1Group, Version: "v1", Resource: resource}
+	existing, err := r.dynclient.Resource(v1GVR).Namespace(legacy.GetNamespace()).Get(legacy.GetName(), metav1.GetOptions{})
+	if err == nil {
+		if _, ok := existing.GetAnnotations()[migratedFromAnnotation]; !ok {
+			objStatus.State = operatorv1alpha1.MigrationSkipped
+			objStatus.Message = "A cert-manager.io object with the same name already exists"
+			return objStatus
+		}
+		// The secret is kept after the object is created, so keeping it is retried if it failed
+		if legacy.GetKind() == "Certificate" && !dryRun {
+			if _, err := r.keepSecret(legacy, false); err != nil {
+				return failed(err)
+			}
+		}
+		objStatus.State = operatorv1alpha1.MigrationMigrated
+		return objStatus
+	} else if !apiErrors.IsNotFound(err) {
+		return failed(err)
+	}
+
+	if dryRun {
+		if legacy.GetKind() == "Certificate" {
+			secretNote, err := r.keepSecret(legacy, true)
+			if err != nil {
+				return failed(err)
+			}
+			if secretNote != "" {
+				notes = append(notes, secretNote)
+			}
+		}
+		sort.Strings(notes)
+		objStatus.Message = strings.Join(notes, "; ")
+		objStatus.State = operatorv1alpha1.MigrationWouldMigrate
+		return objStatus
+	}
+
+	// The object is created before its secret is changed, so that the secret is left as it was if it can't be
+	if _, err := r.dynclient.Resource(v1GVR).Namespace(legacy.GetNamespace()).Create(converted, metav1.CreateOptions{}); err != nil {
+		return failed(err)
+	}
+	if legacy.GetKind() == "Certificate" {
+		if _, err := r.keepSecret(legacy, false); err != nil {
+			return failed(fmt.Errorf("the certificate was created but its secret couldn't be kept: %v", err))
+		}
+	}
+	log.V(1).Info("Migrated object", "kind", objStatus.Kind, "namespace", objStatus.Namespace, "name", objStatus.Name, "notes", notes)
+	objStatus.State = operatorv1alpha1.MigrationMigrated
+	return objStatus
+}
+
+// Prepares the secret issued for a legacy certificate to be taken over by its cert-manager.io
+// equivalent without being reissued. The cert-manager.io annotations that record the issuer are
+// added, and the secret is released from the legacy certificate so it isn't deleted along with it.
+func (r *ReconcileMigration) keepSecret(legacy *unstructured.Unstructured, dryRun bool) (string, error) {
+	secretName, _, err := unstructured.NestedString(legacy.Object, "spec", "secretName")
+	if err != nil || secretName == "" {
+		return "", err
+	}
+	secret, err := r.kubeclient.CoreV1().Secrets(legacy.GetNamespace()).Get(secretName, metav1.GetOptions{})
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	changed := false
+	annotations := secret.GetAnnotations()
+	for k, v := range convertAnnotations(annotations) {
+		if _, ok := annotations[k]; !ok {
+			annotations[k] = v
+			changed = true
+		}
+	}
+	var refs []metav1.OwnerReference
+	for _, ref := range secret.GetOwnerReferences() {
+		if ref.APIVersion == legacy.GetAPIVersion() && ref.Kind == legacy.GetKind() {
+			changed = true
+			continue
+		}
+		refs = append(refs, ref)
+	}
+	if !changed {
+		return "", nil
+	}
+	if dryRun {
+		return fmt.Sprintf("secret %s would be kept for the migrated certificate", secretName), nil
+	}
+
+	secret.SetAnnotations(annotations)
+	secret.SetOwnerReferences(refs)
+	if _, err := r.kubeclient.CoreV1().Secrets(legacy.GetNamespace()).Update(secret); err != nil {
+		return "", err
+	}
+	log.V(1).Info("Updated secret for migrated certificate", "namespace", secret.Namespace, "name", secret.Name)
+	return "", nil
+}
@@ -0,0 +1,238 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package resources
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	admRegv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+)
+
+// LegacyVersion is the cert-manager release serving the certmanager.k8s.io/v1alpha1 API.
+// It is deployed when the CertManager doesn't set a version.
+const LegacyVersion = "0.10.3"
+
+// V1Version is the cert-manager release serving the cert-manager.io/v1 API
+const V1Version = "1.5.4"
+
+// Bundle is a cert-manager release the operator can deploy. It carries everything that
+// differs between releases: the CRDs, images, RBAC rules, args and webhook configuration.
+type Bundle struct {
+	// Version is the cert-manager release
+	Version string
+	// Group is the API group of the certificates, issuers and clusterissuers
+	Group string
+	// APIVersion is the version of the API the cert-manager resources are served at
+	APIVersion string
+	// CRDs is the names of the crds created/used by cert-manager in this release
+	CRDs []string
+
+	// ImageRegistry is the registry the images are pulled from when the CertManager doesn't set one
+	ImageRegistry string
+	// ImageTag is the tag of all of the cert-manager images in this release
+	ImageTag            string
+	ControllerImageName string
+	AcmesolverImageName string
+	CainjectorImageName string
+	WebhookImageName    string
+
+	// APIService is true when the webhook is served through an aggregated apiservice
+	APIService bool
+
+	crds              map[string]*apiext.CustomResourceDefinition
+	clusterRoleRules  []rbacv1.PolicyRule
+	controllerArgs    func(ns, resourceNS, acmesolverImage string) []string
+	cainjectorArgs    func(ns string) []string
+	deployment        func(template *appsv1.Deployment, ns string) *appsv1.Deployment
+	mutatingWebhook   func(ns string) *admRegv1beta1.MutatingWebhookConfiguration
+	validatingWebhook func(ns string) *admRegv1beta1.ValidatingWebhookConfiguration
+	webhookSvc        func(ns string) *corev1.Service
+}
+
+// bundles is a map from the cert-manager release to its bundle
+var bundles = map[string]*Bundle{
+	LegacyVersion: legacyBundle,
+	V1Version:     v1Bundle,
+}
+
+// GetBundle returns the bundle for the given cert-manager release, or the legacy bundle if no release is given
+func GetBundle(version string) (*Bundle, error) {
+	if version == "" {
+		return legacyBundle, nil
+	}
+	bundle, ok := bundles[version]
+	if !ok {
+		return nil, fmt.Errorf("cert-manager version %s is not supported, supported versions are %v", version, Versions())
+	}
+	return bundle, nil
+}
+
+// Versions returns the cert-manager releases the operator can deploy
+func Versions() []string {
+	var versions []string
+	for version := range bundles {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// apiApprovalAnnotation must be set on apiextensions.k8s.io/v1 CRDs in the protected *.k8s.io groups
+const apiApprovalAnnotation = "api-approved.kubernetes.io"
+
+// CRD returns the definition of the crd with the given name, or nil if this release doesn't use a crd by that name.
+// The schema is structural, so the definition can be served as apiextensions.k8s.io/v1.
+func (b *Bundle) CRD(name string) *apiext.CustomResourceDefinition {
+	crd, ok := b.crds[name]
+	if !ok {
+		return nil
+	}
+	crd = crd.DeepCopy()
+	if crd.Spec.Validation == nil {
+		crd.Spec.Validation = &apiext.CustomResourceValidation{
+			OpenAPIV3Schema: &apiext.JSONSchemaProps{Type: "object", XPreserveUnknownFields: &trueVar},
+		}
+	}
+	structural(crd.Spec.Validation.OpenAPIV3Schema)
+
+	// The legacy certmanager.k8s.io group was never approved, which is accepted when it is declared
+	if crd.Spec.Group == "k8s.io" || strings.HasSuffix(crd.Spec.Group, ".k8s.io") {
+		if crd.Annotations == nil {
+			crd.Annotations = make(map[string]string)
+		}
+		crd.Annotations[apiApprovalAnnotation] = "unapproved, served by cert-manager " + b.Version
+	}
+	return crd
+}
+
+// Makes the schema structural by giving a type to every node that lacks one. Nodes with
+// properties are objects and nodes with items are arrays, any other untyped node keeps
+// whatever value it is given.
+func structural(props *apiext.JSONSchemaProps) {
+	if props == nil {
+		return
+	}
+	switch {
+	case props.Type != "" || props.XIntOrString:
+	case len(props.Properties) > 0:
+		props.Type = "object"
+	case props.Items != nil:
+		props.Type = "array"
+	default:
+		props.XPreserveUnknownFields = &trueVar
+	}
+	for name, prop := range props.Properties {
+		structural(&prop)
+		props.Properties[name] = prop
+	}
+	if props.Items != nil {
+		structural(props.Items.Schema)
+	}
+	if props.AdditionalProperties != nil {
+		structural(props.AdditionalProperties.Schema)
+	}
+}
+
+// ClusterRole returns the cluster role used by cert-manager service
+func (b *Bundle) ClusterRole() *rbacv1.ClusterRole {
+	role := defaultClusterRole.DeepCopy()
+	role.Rules = make([]rbacv1.PolicyRule, len(b.clusterRoleRules))
+	for i := range b.clusterRoleRules {
+		b.clusterRoleRules[i].DeepCopyInto(&role.Rules[i])
+	}
+	return role
+}
+
+// ControllerArgs returns the args of the cert-manager-controller deployed in the given namespace
+func (b *Bundle) ControllerArgs(ns, resourceNS, acmesolverImage string) []string {
+	return b.controllerArgs(ns, resourceNS, acmesolverImage)
+}
+
+// CainjectorArgs returns the args of the cert-manager-cainjector deployed in the given namespace
+func (b *Bundle) CainjectorArgs(ns string) []string {
+	return b.cainjectorArgs(ns)
+}
+
+// ControllerDeployment returns the deployment for the cert-manager-controller in the given namespace
+func (b *Bundle) ControllerDeployment(ns string) *appsv1.Deployment {
+	return b.deployment(controllerDeployment, ns)
+}
+
+// WebhookDeployment returns the deployment for the cert-manager-webhook in the given namespace
+func (b *Bundle) WebhookDeployment(ns string) *appsv1.Deployment {
+	return b.deployment(webhookDeployment, ns)
+}
+
+// CainjectorDeployment returns the deployment for the cert-manager-cainjector in the given namespace
+func (b *Bundle) CainjectorDeployment(ns string) *appsv1.Deployment {
+	return b.deployment(cainjectorDeployment, ns)
+}
+
+// MutatingWebhook returns the mutating webhook definition for cert-manager-webhook deployed in the given namespace
+func (b *Bundle) MutatingWebhook(ns string) *admRegv1beta1.MutatingWebhookConfiguration {
+	return b.mutatingWebhook(ns)
+}
+
+// ValidatingWebhook returns the validating webhook definition for cert-manager-webhook,
+// which skips validating the resources in the namespace cert-manager is deployed in
+func (b *Bundle) ValidatingWebhook(ns string) *admRegv1beta1.ValidatingWebhookConfiguration {
+	return b.validatingWebhook(ns)
+}
+
+// WebhookSvc returns the service definition for cert-manager-webhook in the given namespace
+func (b *Bundle) WebhookSvc(ns string) *corev1.Service {
+	return b.webhookSvc(ns)
+}
+
+// legacyBundle is cert-manager 0.10.3, serving the certmanager.k8s.io/v1alpha1 API
+var legacyBundle = &Bundle{
+	Version:             LegacyVersion,
+	Group:               GroupVersion,
+	APIVersion:          CRDVersion,
+	CRDs:                crdNames(crdMap),
+	ImageRegistry:       ImageRegistry,
+	ImageTag:            ControllerImageVersion,
+	ControllerImageName: ControllerImageName,
+	AcmesolverImageName: AcmesolverImageName,
+	CainjectorImageName: CainjectorImageName,
+	WebhookImageName:    WebhookImageName,
+	APIService:          true,
+	crds:                crdMap,
+	clusterRoleRules:    defaultClusterRole.Rules,
+	controllerArgs:      legacyControllerArgs,
+	cainjectorArgs:      legacyCainjectorArgs,
+	deployment:          deployment,
+	mutatingWebhook:     func(string) *admRegv1beta1.MutatingWebhookConfiguration { return mutatingWebhook.DeepCopy() },
+	validatingWebhook:   legacyValidatingWebhook,
+	webhookSvc:          legacyWebhookSvc,
+}
+
+// Returns the sorted names of the crds in the map
+func crdNames(crds map[string]*apiext.CustomResourceDefinition) []string {
+	var names []string
+	for name := range crds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
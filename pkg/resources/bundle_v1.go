@@ -0,0 +1,351 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package resources
+
+import (
+	"strconv"
+
+	admRegv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// V1Group is the API group of the certificates, issuers and clusterissuers in the v1 release
+const V1Group = "cert-manager.io"
+
+// V1AcmeGroup is the API group of the orders and challenges in the v1 release
+const V1AcmeGroup = "acme.cert-manager.io"
+
+// v1WebhookCASecret is the secret the v1 cert-manager-webhook stores the CA of its serving certificate in
+const v1WebhookCASecret = "cert-manager-webhook-ca"
+
+// v1WebhookPort is the port the v1 cert-manager-webhook serves on. It isn't the upstream default
+// of 10250, which is taken by the kubelet when the webhook runs on the host network.
+const v1WebhookPort = 10260
+
+// v1WebhookHealthPort is the port the v1 cert-manager-webhook serves its health endpoints on
+const v1WebhookHealthPort = 6080
+
+var v1MutationPath = "/mutate"
+var v1ValPath = "/validate"
+var v1WebhookTimeout int32 = 10
+
+// v1Bundle is cert-manager 1.5.4, serving the cert-manager.io/v1 API
+var v1Bundle = &Bundle{
+	Version:             V1Version,
+	Group:               V1Group,
+	APIVersion:          "v1",
+	CRDs:                crdNames(v1CRDMap),
+	ImageRegistry:       "quay.io/jetstack",
+	ImageTag:            "v" + V1Version,
+	ControllerImageName: "cert-manager-controller",
+	AcmesolverImageName: "cert-manager-acmesolver",
+	CainjectorImageName: "cert-manager-cainjector",
+	WebhookImageName:    "cert-manager-webhook",
+	APIService:          false,
+	crds:                v1CRDMap,
+	clusterRoleRules:    v1ClusterRoleRules,
+	controllerArgs:      v1ControllerArgs,
+	cainjectorArgs:      v1CainjectorArgs,
+	deployment:          v1Deployment,
+	mutatingWebhook:     v1MutatingWebhook,
+	validatingWebhook:   v1ValidatingWebhook,
+	webhookSvc:          v1WebhookSvc,
+}
+
+func v1ControllerArgs(ns, resourceNS, acmesolverImage string) []string {
+	return []string{
+		"--v=2",
+		"--cluster-resource-namespace=" + resourceNS,
+		"--leader-election-namespace=" + ns,
+		AcmesolverImageArg + acmesolverImage,
+	}
+}
+
+func v1CainjectorArgs(ns string) []string {
+	return []string{
+		"--v=2",
+		"--leader-election-namespace=" + ns,
+	}
+}
+
+// Returns a copy of the deployment template adapted to the upstream images. These images have
+// no shell, so the exec probes of the legacy images are replaced by HTTP probes of the endpoints
+// they serve: the webhook serves health endpoints and the controller its metrics. The cainjector
+// serves none, so it isn't probed. The webhook manages its own serving certificate, so it doesn't
+// need the serving secret.
+func v1Deployment(template *appsv1.Deployment, ns string) *appsv1.Deployment {
+	deploy := deployment(template, ns)
+	spec := &deploy.Spec.Template.Spec
+	container := &spec.Containers[0]
+	container.LivenessProbe = nil
+	container.ReadinessProbe = nil
+
+	if deploy.Name == CertManagerControllerName {
+		container.LivenessProbe = &corev1.Probe{
+			Handler: corev1.Handler{
+				HTTPGet: controllerMetricsHealth.DeepCopy(),
+			},
+			InitialDelaySeconds: initialDelaySecondsLiveness,
+			TimeoutSeconds:      timeoutSecondsLiveness,
+		}
+		container.ReadinessProbe = &corev1.Probe{
+			Handler: corev1.Handler{
+				HTTPGet: controllerMetricsHealth.DeepCopy(),
+			},
+			InitialDelaySeconds: initialDelaySecondsReadiness,
+			TimeoutSeconds:      timeoutSecondsReadiness,
+		}
+	}
+
+	if deploy.Name == CertManagerWebhookName {
+		deploy.Annotations = nil
+		deploy.Spec.Template.Annotations = make(map[string]string, len(podAnnotations))
+		for k, v := range podAnnotations {
+			deploy.Spec.Template.Annotations[k] = v
+		}
+		spec.Volumes = nil
+		container.VolumeMounts = nil
+		container.Args = []string{
+			"--v=2",
+			"--secure-port=" + strconv.Itoa(v1WebhookPort),
+			"--healthz-port=" + strconv.Itoa(v1WebhookHealthPort),
+			"--dynamic-serving-ca-secret-namespace=" + ns,
+			"--dynamic-serving-ca-secret-name=" + v1WebhookCASecret,
+			"--dynamic-serving-dns-names=cert-manager-webhook,cert-manager-webhook." + ns + ",cert-manager-webhook." + ns + ".svc",
+		}
+		container.Ports = []corev1.ContainerPort{
+			{
+				Name:          "https",
+				ContainerPort: v1WebhookPort,
+				Protocol:      corev1.ProtocolTCP,
+			},
+		}
+		container.LivenessProbe = &corev1.Probe{
+			Handler: corev1.Handler{
+				HTTPGet: &corev1.HTTPGetAction{Path: "/livez", Port: intstr.FromInt(v1WebhookHealthPort), Scheme: corev1.URISchemeHTTP},
+			},
+			InitialDelaySeconds: 60,
+			PeriodSeconds:       10,
+			TimeoutSeconds:      1,
+			SuccessThreshold:    1,
+			FailureThreshold:    3,
+		}
+		container.ReadinessProbe = &corev1.Probe{
+			Handler: corev1.Handler{
+				HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromInt(v1WebhookHealthPort), Scheme: corev1.URISchemeHTTP},
+			},
+			InitialDelaySeconds: 5,
+			PeriodSeconds:       5,
+			TimeoutSeconds:      1,
+			SuccessThreshold:    1,
+			FailureThreshold:    3,
+		}
+	}
+	return deploy
+}
+
+func v1MutatingWebhook(ns string) *admRegv1beta1.MutatingWebhookConfiguration {
+	return &admRegv1beta1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        CertManagerWebhookName,
+			Labels:      copyLabels(webhookLabelMap),
+			Annotations: map[string]string{"cert-manager.io/inject-ca-from-secret": ns + "/" + v1WebhookCASecret},
+		},
+		Webhooks: []admRegv1beta1.MutatingWebhook{
+			{
+				Name:                    "webhook.cert-manager.io",
+				ClientConfig:            v1WebhookClientConfig(ns, v1MutationPath),
+				Rules:                   v1WebhookRules(),
+				FailurePolicy:           &failPolicy,
+				SideEffects:             &sideEffect,
+				TimeoutSeconds:          &v1WebhookTimeout,
+				AdmissionReviewVersions: []string{"v1", "v1beta1"},
+			},
+		},
+	}
+}
+
+func v1ValidatingWebhook(ns string) *admRegv1beta1.ValidatingWebhookConfiguration {
+	return &admRegv1beta1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        CertManagerWebhookName,
+			Labels:      copyLabels(webhookLabelMap),
+			Annotations: map[string]string{"cert-manager.io/inject-ca-from-secret": ns + "/" + v1WebhookCASecret},
+		},
+		Webhooks: []admRegv1beta1.ValidatingWebhook{
+			{
+				Name:                    "webhook.cert-manager.io",
+				ClientConfig:            v1WebhookClientConfig(ns, v1ValPath),
+				Rules:                   v1WebhookRules(),
+				FailurePolicy:           &failPolicy,
+				SideEffects:             &sideEffect,
+				TimeoutSeconds:          &v1WebhookTimeout,
+				AdmissionReviewVersions: []string{"v1", "v1beta1"},
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{
+							Key:      V1Group + "/disable-validation",
+							Operator: metav1.LabelSelectorOpNotIn,
+							Values:   []string{"true"},
+						},
+						{
+							Key:      "name",
+							Operator: metav1.LabelSelectorOpNotIn,
+							Values:   []string{ns},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func v1WebhookClientConfig(ns, path string) admRegv1beta1.WebhookClientConfig {
+	return admRegv1beta1.WebhookClientConfig{
+		Service: &admRegv1beta1.ServiceReference{
+			Namespace: ns,
+			Name:      CertManagerWebhookName,
+			Path:      &path,
+		},
+	}
+}
+
+func v1WebhookRules() []admRegv1beta1.RuleWithOperations {
+	return []admRegv1beta1.RuleWithOperations{
+		{
+			Operations: []admRegv1beta1.OperationType{
+				admRegv1beta1.Create,
+				admRegv1beta1.Update,
+			},
+			Rule: admRegv1beta1.Rule{
+				APIGroups:   []string{V1Group, V1AcmeGroup},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"*/*"},
+			},
+		},
+	}
+}
+
+func v1WebhookSvc(ns string) *corev1.Service {
+	svc := webhookSvc.DeepCopy()
+	svc.Namespace = ns
+	svc.Spec.Ports[0].TargetPort = intstr.IntOrString{IntVal: v1WebhookPort}
+	return svc
+}
+
+// Returns a copy of the labels that shares no state with the package's label maps
+func copyLabels(labels map[string]string) map[string]string {
+	copied := make(map[string]string, len(labels))
+	for k, v := range labels {
+		copied[k] = v
+	}
+	return copied
+}
+
+var v1ClusterRoleRules = []rbacv1.PolicyRule{
+	{
+		Verbs:     []string{"get", "list", "watch", "create", "update", "delete", "patch"},
+		APIGroups: []string{""},
+		Resources: []string{"secrets", "configmaps"},
+	},
+	{
+		Verbs:     []string{"*"},
+		APIGroups: []string{V1Group},
+		Resources: []string{"certificates", "certificaterequests", "issuers", "clusterissuers"},
+	},
+	{
+		Verbs:     []string{"*"},
+		APIGroups: []string{V1AcmeGroup},
+		Resources: []string{"orders", "challenges"},
+	},
+	{
+		Verbs:     []string{"update"},
+		APIGroups: []string{V1Group, V1AcmeGroup},
+		Resources: []string{
+			"certificates/status",
+			"certificaterequests/status",
+			"challenges/status",
+			"clusterissuers/status",
+			"issuers/status",
+			"orders/status",
+			"certificates/finalizers",
+			"certificaterequests/finalizers",
+			"challenges/finalizers",
+			"orders/finalizers",
+		},
+	},
+	{
+		Verbs:     []string{"create", "patch"},
+		APIGroups: []string{""},
+		Resources: []string{"events"},
+	},
+	{
+		Verbs:     []string{"get", "list", "watch", "create", "delete"},
+		APIGroups: []string{""},
+		Resources: []string{"pods", "services"},
+	},
+	{
+		Verbs:     []string{"get", "list", "watch", "create", "delete", "update"},
+		APIGroups: []string{"networking.k8s.io", "extensions"},
+		Resources: []string{"ingresses"},
+	},
+	{
+		Verbs:     []string{"update"},
+		APIGroups: []string{"networking.k8s.io", "extensions"},
+		Resources: []string{"ingresses/finalizers"},
+	},
+	{
+		Verbs:     []string{"create"},
+		APIGroups: []string{"route.openshift.io"},
+		Resources: []string{"routes/custom-host"},
+	},
+	{
+		Verbs:     []string{"get", "create", "update"},
+		APIGroups: []string{"coordination.k8s.io"},
+		Resources: []string{"leases"},
+	},
+	{
+		Verbs:     []string{"get", "list", "watch", "update"},
+		APIGroups: []string{"apiextensions.k8s.io"},
+		Resources: []string{"customresourcedefinitions"},
+	},
+	{
+		Verbs:     []string{"get", "list", "watch", "update"},
+		APIGroups: []string{"admissionregistration.k8s.io"},
+		Resources: []string{"mutatingwebhookconfigurations", "validatingwebhookconfigurations"},
+	},
+	{
+		Verbs:     []string{"get", "list", "watch", "update"},
+		APIGroups: []string{"apiregistration.k8s.io"},
+		Resources: []string{"apiservices"},
+	},
+	{
+		Verbs:     []string{"create"},
+		APIGroups: []string{"authorization.k8s.io"},
+		Resources: []string{"subjectaccessreviews"},
+	},
+	{
+		Verbs:         []string{"use"},
+		APIGroups:     []string{"security.openshift.io"},
+		Resources:     []string{"securitycontextconstraints"},
+		ResourceNames: []string{"restricted", "hostnetwork"},
+	},
+}
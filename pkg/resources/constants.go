@@ -20,13 +20,14 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-// TrueVar the variable representing the boolean value true
-var TrueVar = true
+// trueVar the variable representing the boolean value true
+var trueVar = true
 
-// FalseVar the variable representing the boolean value false
-var FalseVar = false
+// falseVar the variable representing the boolean value false
+var falseVar = false
 
 // CPU quantities
 var cpu100 = resource.NewMilliQuantity(100, resource.DecimalSI) // 100m
@@ -40,8 +41,8 @@ var replicaCount int32 = 1
 
 const certManagerComponentName = "cert-manager"
 
-// ControllerLabelMap is a map of all the labels used by cert-manager-controller
-var ControllerLabelMap = map[string]string{
+// controllerLabelMap is a map of all the labels used by cert-manager-controller
+var controllerLabelMap = map[string]string{
 	"app":                          "ibm-cert-manager-controller",
 	"app.kubernetes.io/name":       "ibm-cert-manager-controller",
 	"app.kubernetes.io/component":  certManagerComponentName,
@@ -50,8 +51,8 @@ var ControllerLabelMap = map[string]string{
 	"release":                      certManagerComponentName,
 }
 
-// WebhookLabelMap is a map of all the labels used by the cert-manager-webhook
-var WebhookLabelMap = map[string]string{
+// webhookLabelMap is a map of all the labels used by the cert-manager-webhook
+var webhookLabelMap = map[string]string{
 	"app":                          "ibm-cert-manager-webhook",
 	"app.kubernetes.io/name":       "ibm-cert-manager-webhook",
 	"app.kubernetes.io/component":  certManagerComponentName,
@@ -61,8 +62,8 @@ var WebhookLabelMap = map[string]string{
 	"watcher.ibm.com/opt-in":       "true",
 }
 
-// CainjectorLabelMap is a map of all the labels used by the cert-manager-cainjector
-var CainjectorLabelMap = map[string]string{
+// cainjectorLabelMap is a map of all the labels used by the cert-manager-cainjector
+var cainjectorLabelMap = map[string]string{
 	"app":                          "ibm-cert-manager-cainjector",
 	"app.kubernetes.io/name":       "ibm-cert-manager-cainjector",
 	"app.kubernetes.io/component":  certManagerComponentName,
@@ -71,8 +72,8 @@ var CainjectorLabelMap = map[string]string{
 	"release":                      certManagerComponentName,
 }
 
-// ConfigmapWatcherLabelMap is the labels for the configmap watcher in map format
-var ConfigmapWatcherLabelMap = map[string]string{
+// configmapWatcherLabelMap is the labels for the configmap watcher in map format
+var configmapWatcherLabelMap = map[string]string{
 	"app.kubernetes.io/name":       ConfigmapWatcherName,
 	"app.kubernetes.io/component":  certManagerComponentName,
 	"app.kubernetes.io/managed-by": "operator",
@@ -80,8 +81,14 @@ var ConfigmapWatcherLabelMap = map[string]string{
 	"release":                      certManagerComponentName,
 }
 
-// PodAnnotations are the annotations required for a pod
-var PodAnnotations = map[string]string{"openshift.io/scc": "restricted", "productName": "IBM Cloud Platform Common Services", "productID": "068a62892a1e4db39641342e592daa25", "productVersion": "3.3.0", "productMetric": "FREE"}
+// sccAnnotation requests the security context constraint a pod runs with on OpenShift
+const sccAnnotation = "openshift.io/scc"
+
+// hostnameTopologyKey is the node label the replicas of a deployment are spread across
+const hostnameTopologyKey = "kubernetes.io/hostname"
+
+// podAnnotations are the annotations required for a pod
+var podAnnotations = map[string]string{"openshift.io/scc": "restricted", "productName": "IBM Cloud Platform Common Services", "productID": "068a62892a1e4db39641342e592daa25", "productVersion": "3.3.0", "productMetric": "FREE"}
 
 var securityAnnotationWebhook = map[string]string{"openshift.io/scc": "hostnetwork",
 	"productName":    "IBM Cloud Platform Common Services",
@@ -168,6 +175,8 @@ const ClusterRoleName = "cert-manager"
 var runAsNonRoot = true
 
 // Liveness/Readiness Probe
+// The configmap-watcher serves no endpoint, so only its process is checked. The controller and
+// cainjector serve no health endpoint, they are ready once they serve their metrics.
 var initialDelaySecondsLiveness int32 = 30
 var timeoutSecondsLiveness int32 = 5
 var livenessExecActionController = v1.ExecAction{
@@ -176,26 +185,37 @@ var livenessExecActionController = v1.ExecAction{
 var livenessExecActionCainjector = v1.ExecAction{
 	Command: []string{"sh", "-c", "pgrep cainjector -l"},
 }
-var livenessExecActionWebhook = v1.ExecAction{
-	Command: []string{"sh", "-c", "pgrep webhook -l"},
-}
 var livenessExecActionConfigmapWatcher = v1.ExecAction{
 	Command: []string{"sh", "-c", "pgrep watcher -l"},
 }
 
 var initialDelaySecondsReadiness int32 = 10
 var timeoutSecondsReadiness int32 = 2
-var readinessExecActionController = v1.ExecAction{
-	Command: []string{"sh", "-c", "exec echo start cert-manager"},
+var readinessExecActionConfigmapWatcher = v1.ExecAction{
+	Command: []string{"sh", "-c", "exec echo start configmap-watcher"},
 }
-var readinessExecActionCainjector = v1.ExecAction{
-	Command: []string{"sh", "-c", "exec echo start cert-manager cainjector"},
+
+// controllerMetricsHealth checks that cert-manager-controller serves its metrics, as it does in every release
+var controllerMetricsHealth = v1.HTTPGetAction{
+	Path:   "/metrics",
+	Port:   intstr.FromInt(controllerMetricsPort),
+	Scheme: v1.URISchemeHTTP,
 }
-var readinessExecActionWebhook = v1.ExecAction{
-	Command: []string{"sh", "-c", "exec echo start cert-manager webhook"},
+
+// legacyCainjectorMetricsHealth checks that the legacy cainjector serves the metrics of its
+// controller-runtime manager, on the manager's default port
+var legacyCainjectorMetricsHealth = v1.HTTPGetAction{
+	Path:   "/metrics",
+	Port:   intstr.FromInt(8080),
+	Scheme: v1.URISchemeHTTP,
 }
-var readinessExecActionConfigmapWatcher = v1.ExecAction{
-	Command: []string{"sh", "-c", "exec echo start configmap-watcher"},
+
+// legacyWebhookHealth is the health check of the legacy webhook, an aggregated API server
+// which serves it without authentication on its secure port
+var legacyWebhookHealth = v1.HTTPGetAction{
+	Path:   "/healthz",
+	Port:   intstr.FromInt(1443),
+	Scheme: v1.URISchemeHTTPS,
 }
 
 // Cert-manager args
@@ -209,7 +229,10 @@ const ResourceNS = "--cluster-resource-namespace=ibm-common-services"
 const leaderElectNS = "--leader-election-namespace=cert-manager"
 
 // AcmeSolverArg is the acme solver image to use for the cert-manager-controller
-const AcmeSolverArg = "--acme-http01-solver-image=" + acmesolverImage
+const AcmeSolverArg = AcmesolverImageArg + acmesolverImage
+
+// AcmesolverImageArg is the cert-manager-controller's argument for the acmesolver image
+const AcmesolverImageArg = "--acme-http01-solver-image="
 
 const webhookNSArg = "--webhook-namespace=" + DeployNamespace
 const webhookCASecretArg = "--webhook-ca-secret=cert-manager-webhook-ca"
@@ -218,11 +241,27 @@ const webhookServingSecretArg = "--webhook-serving-secret=" + WebhookServingSecr
 const webhookDNSNamesArg = "--webhook-dns-names=cert-manager-webhook,cert-manager-webhook.cert-manager,cert-manager-webhook.cert-manager.svc"
 const controllersArg = "--controllers=certificates,issuers,clusterissuers,orders,challenges,webhook-bootstrap"
 
-// DefaultArgs are the default arguments use for cert-manager-controller
-var DefaultArgs = []string{webhookCASecretArg, webhookServingSecretArg, controllersArg}
+// Returns the arguments of the cert-manager-controller in the legacy release
+func legacyControllerArgs(ns, resourceNS, acmesolverImage string) []string {
+	return []string{
+		webhookCASecretArg,
+		webhookServingSecretArg,
+		controllersArg,
+		AcmesolverImageArg + acmesolverImage,
+		"--cluster-resource-namespace=" + resourceNS,
+		"--leader-election-namespace=" + ns,
+		"--webhook-namespace=" + ns,
+		"--webhook-dns-names=cert-manager-webhook,cert-manager-webhook." + ns + ",cert-manager-webhook." + ns + ".svc",
+	}
+}
 
-// CRDs is the list of crds created/used by cert-manager in this version
-var CRDs = [5]string{"certificates", "issuers", "clusterissuers", "orders", "challenges"}
+// Returns the arguments of the cert-manager-cainjector in the legacy release. Its leader election
+// defaults to kube-system, which the operator has no access to.
+func legacyCainjectorArgs(ns string) []string {
+	return []string{
+		"--leader-election-namespace=" + ns,
+	}
+}
 
 // GroupVersion is the cert-manager's crd group version
 const GroupVersion = "certmanager.k8s.io"
@@ -230,15 +269,17 @@ const GroupVersion = "certmanager.k8s.io"
 //CRDVersion is the cert-manager's crd version
 const CRDVersion = "v1alpha1"
 
-// NamespaceDef is the namespace spec for the cert-manager services and will be where the service is deployed
-var NamespaceDef = &v1.Namespace{
-	ObjectMeta: metav1.ObjectMeta{
-		Name: DeployNamespace,
-		Labels: map[string]string{
-			"certmanager.k8s.io/disable-validation": "true",
+// Namespace returns the namespace spec for the cert-manager services, which is where the service is deployed
+func Namespace(ns string) *v1.Namespace {
+	return &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: ns,
+			Labels: map[string]string{
+				"certmanager.k8s.io/disable-validation": "true",
+			},
+		},
+		Spec: v1.NamespaceSpec{
+			Finalizers: []v1.FinalizerName{"kubernetes"},
 		},
-	},
-	Spec: v1.NamespaceSpec{
-		Finalizers: []v1.FinalizerName{"kubernetes"},
-	},
+	}
 }
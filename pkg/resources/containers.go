@@ -23,9 +23,9 @@ import (
 
 var containerSecurityGeneral = &corev1.SecurityContext{
 	RunAsNonRoot:             &runAsNonRoot,
-	AllowPrivilegeEscalation: &FalseVar,
-	ReadOnlyRootFilesystem:   &TrueVar,
-	Privileged:               &FalseVar,
+	AllowPrivilegeEscalation: &falseVar,
+	ReadOnlyRootFilesystem:   &trueVar,
+	Privileged:               &falseVar,
 	Capabilities: &corev1.Capabilities{
 		Drop: []corev1.Capability{
 			"ALL",
@@ -35,9 +35,9 @@ var containerSecurityGeneral = &corev1.SecurityContext{
 
 var containerSecurityWebhook = &corev1.SecurityContext{
 	RunAsNonRoot:             &runAsNonRoot,
-	AllowPrivilegeEscalation: &FalseVar,
-	ReadOnlyRootFilesystem:   &FalseVar,
-	Privileged:               &FalseVar,
+	AllowPrivilegeEscalation: &falseVar,
+	ReadOnlyRootFilesystem:   &falseVar,
+	Privileged:               &falseVar,
 	Capabilities: &corev1.Capabilities{
 		Drop: []corev1.Capability{
 			"ALL",
@@ -54,6 +54,11 @@ var cpuMemory = corev1.ResourceRequirements{
 		corev1.ResourceMemory: *memory300},
 }
 
+// DefaultResources returns a copy of the compute resources the operand containers are deployed with by default
+func DefaultResources() corev1.ResourceRequirements {
+	return *cpuMemory.DeepCopy()
+}
+
 var controllerContainer = corev1.Container{
 	Name:            CertManagerControllerName,
 	Image:           controllerImage,
@@ -82,7 +87,7 @@ var controllerContainer = corev1.Container{
 	},
 	ReadinessProbe: &corev1.Probe{
 		Handler: corev1.Handler{
-			Exec: &readinessExecActionController,
+			HTTPGet: &controllerMetricsHealth,
 		},
 		InitialDelaySeconds: initialDelaySecondsReadiness,
 		TimeoutSeconds:      timeoutSecondsReadiness,
@@ -108,14 +113,14 @@ var webhookContainer = corev1.Container{
 	},
 	LivenessProbe: &corev1.Probe{
 		Handler: corev1.Handler{
-			Exec: &livenessExecActionWebhook,
+			HTTPGet: &legacyWebhookHealth,
 		},
 		InitialDelaySeconds: initialDelaySecondsLiveness,
 		TimeoutSeconds:      timeoutSecondsLiveness,
 	},
 	ReadinessProbe: &corev1.Probe{
 		Handler: corev1.Handler{
-			Exec: &readinessExecActionWebhook,
+			HTTPGet: &legacyWebhookHealth,
 		},
 		InitialDelaySeconds: initialDelaySecondsReadiness,
 		TimeoutSeconds:      timeoutSecondsReadiness,
@@ -153,7 +158,7 @@ var cainjectorContainer = corev1.Container{
 	},
 	ReadinessProbe: &corev1.Probe{
 		Handler: corev1.Handler{
-			Exec: &readinessExecActionCainjector,
+			HTTPGet: &legacyCainjectorMetricsHealth,
 		},
 		InitialDelaySeconds: initialDelaySecondsReadiness,
 		TimeoutSeconds:      timeoutSecondsReadiness,
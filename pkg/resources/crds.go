@@ -21,17 +21,17 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// CRDMap a map from the crd name to the definition of that crd
-var CRDMap = map[string]*apiext.CustomResourceDefinition{
-	"certificates":   certificateCRD,
-	"issuers":        issuerCRD,
-	"clusterissuers": clusterIssuerCRD,
-	"orders":         orderCRD,
-	"challenges":     challengeCRD,
+// crdMap a map from the crd name to the definition of that crd in the legacy release
+var crdMap = map[string]*apiext.CustomResourceDefinition{
+	"certificates." + GroupVersion:   certificateCRD,
+	"issuers." + GroupVersion:        issuerCRD,
+	"clusterissuers." + GroupVersion: clusterIssuerCRD,
+	"orders." + GroupVersion:         orderCRD,
+	"challenges." + GroupVersion:     challengeCRD,
 }
 
 var certificateCRD = &apiext.CustomResourceDefinition{
-	ObjectMeta: metav1.ObjectMeta{Name: "certificates.certmanager.k8s.io", Labels: ControllerLabelMap},
+	ObjectMeta: metav1.ObjectMeta{Name: "certificates.certmanager.k8s.io", Labels: controllerLabelMap},
 	Spec: apiext.CustomResourceDefinitionSpec{
 		Group:   GroupVersion,
 		Version: CRDVersion,
@@ -346,7 +346,7 @@ var certificateCRD = &apiext.CustomResourceDefinition{
 }
 
 var issuerCRD = &apiext.CustomResourceDefinition{
-	ObjectMeta: metav1.ObjectMeta{Name: "issuers.certmanager.k8s.io", Labels: ControllerLabelMap},
+	ObjectMeta: metav1.ObjectMeta{Name: "issuers.certmanager.k8s.io", Labels: controllerLabelMap},
 	Spec: apiext.CustomResourceDefinitionSpec{
 		Group:   GroupVersion,
 		Version: CRDVersion,
@@ -1862,7 +1862,7 @@ var issuerCRD = &apiext.CustomResourceDefinition{
 }
 
 var clusterIssuerCRD = &apiext.CustomResourceDefinition{
-	ObjectMeta: metav1.ObjectMeta{Name: "clusterissuers.certmanager.k8s.io", Labels: ControllerLabelMap},
+	ObjectMeta: metav1.ObjectMeta{Name: "clusterissuers.certmanager.k8s.io", Labels: controllerLabelMap},
 	Spec: apiext.CustomResourceDefinitionSpec{
 		Group:   GroupVersion,
 		Version: CRDVersion,
@@ -3378,7 +3378,7 @@ var clusterIssuerCRD = &apiext.CustomResourceDefinition{
 }
 
 var orderCRD = &apiext.CustomResourceDefinition{
-	ObjectMeta: metav1.ObjectMeta{Name: "orders.certmanager.k8s.io", Labels: ControllerLabelMap},
+	ObjectMeta: metav1.ObjectMeta{Name: "orders.certmanager.k8s.io", Labels: controllerLabelMap},
 	Spec: apiext.CustomResourceDefinitionSpec{
 		Group:   GroupVersion,
 		Version: CRDVersion,
@@ -3399,7 +3399,7 @@ var orderCRD = &apiext.CustomResourceDefinition{
 }
 
 var challengeCRD = &apiext.CustomResourceDefinition{
-	ObjectMeta: metav1.ObjectMeta{Name: "challenges.certmanager.k8s.io", Labels: ControllerLabelMap},
+	ObjectMeta: metav1.ObjectMeta{Name: "challenges.certmanager.k8s.io", Labels: controllerLabelMap},
 	Spec: apiext.CustomResourceDefinitionSpec{
 		Group:   GroupVersion,
 		Version: CRDVersion,
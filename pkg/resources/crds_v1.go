@@ -0,0 +1,123 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package resources
+
+import (
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// v1CRDMap a map from the crd name to the definition of that crd in the v1 release
+var v1CRDMap = map[string]*apiext.CustomResourceDefinition{
+	"certificates." + V1Group:        v1CRD(V1Group, "certificates", "Certificate", apiext.NamespaceScoped, []string{"cert", "certs"}, certificateColumns),
+	"certificaterequests." + V1Group: v1CRD(V1Group, "certificaterequests", "CertificateRequest", apiext.NamespaceScoped, []string{"cr", "crs"}, certificateRequestColumns),
+	"issuers." + V1Group:             v1CRD(V1Group, "issuers", "Issuer", apiext.NamespaceScoped, nil, issuerColumns),
+	"clusterissuers." + V1Group:      v1CRD(V1Group, "clusterissuers", "ClusterIssuer", apiext.ClusterScoped, nil, issuerColumns),
+	"orders." + V1AcmeGroup:          v1CRD(V1AcmeGroup, "orders", "Order", apiext.NamespaceScoped, nil, orderColumns),
+	"challenges." + V1AcmeGroup:      v1CRD(V1AcmeGroup, "challenges", "Challenge", apiext.NamespaceScoped, nil, challengeColumns),
+}
+
+// Returns the definition of a v1 crd. The operator doesn't validate the spec and status of the
+// cert-manager resources, the cert-manager-webhook does, so the schema keeps their fields as they are.
+func v1CRD(group, plural, kind string, scope apiext.ResourceScope, shortNames []string, columns []apiext.CustomResourceColumnDefinition) *apiext.CustomResourceDefinition {
+	return &apiext.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: plural + "." + group, Labels: controllerLabelMap},
+		Spec: apiext.CustomResourceDefinitionSpec{
+			Group: group,
+			Scope: scope,
+			Names: apiext.CustomResourceDefinitionNames{
+				Plural:     plural,
+				Kind:       kind,
+				ShortNames: shortNames,
+				Categories: []string{"cert-manager"},
+			},
+			Versions: []apiext.CustomResourceDefinitionVersion{
+				{
+					Name:    "v1",
+					Served:  true,
+					Storage: true,
+				},
+			},
+			Subresources: &apiext.CustomResourceSubresources{
+				Status: &apiext.CustomResourceSubresourceStatus{},
+			},
+			AdditionalPrinterColumns: columns,
+			Validation: &apiext.CustomResourceValidation{
+				OpenAPIV3Schema: &apiext.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]apiext.JSONSchemaProps{
+						"apiVersion": {Type: "string"},
+						"kind":       {Type: "string"},
+						"metadata":   {Type: "object"},
+						"spec":       {Type: "object", XPreserveUnknownFields: &trueVar},
+						"status":     {Type: "object", XPreserveUnknownFields: &trueVar},
+					},
+				},
+			},
+			PreserveUnknownFields: &falseVar,
+		},
+	}
+}
+
+var readyColumn = apiext.CustomResourceColumnDefinition{
+	JSONPath: `.status.conditions[?(@.type=="Ready")].status`,
+	Name:     "Ready",
+	Type:     "string",
+}
+
+var ageColumn = apiext.CustomResourceColumnDefinition{
+	JSONPath: ".metadata.creationTimestamp",
+	Name:     "Age",
+	Type:     "date",
+}
+
+var certificateColumns = []apiext.CustomResourceColumnDefinition{
+	readyColumn,
+	{JSONPath: ".spec.secretName", Name: "Secret", Type: "string"},
+	{JSONPath: ".spec.issuerRef.name", Name: "Issuer", Type: "string", Priority: 1},
+	{JSONPath: `.status.conditions[?(@.type=="Ready")].message`, Name: "Status", Type: "string", Priority: 1},
+	ageColumn,
+}
+
+var certificateRequestColumns = []apiext.CustomResourceColumnDefinition{
+	{JSONPath: `.status.conditions[?(@.type=="Approved")].status`, Name: "Approved", Type: "string"},
+	{JSONPath: `.status.conditions[?(@.type=="Denied")].status`, Name: "Denied", Type: "string"},
+	readyColumn,
+	{JSONPath: ".spec.issuerRef.name", Name: "Issuer", Type: "string"},
+	{JSONPath: ".spec.username", Name: "Requestor", Type: "string"},
+	ageColumn,
+}
+
+var issuerColumns = []apiext.CustomResourceColumnDefinition{
+	readyColumn,
+	{JSONPath: `.status.conditions[?(@.type=="Ready")].message`, Name: "Status", Type: "string", Priority: 1},
+	ageColumn,
+}
+
+var orderColumns = []apiext.CustomResourceColumnDefinition{
+	{JSONPath: ".status.state", Name: "State", Type: "string"},
+	{JSONPath: ".spec.issuerRef.name", Name: "Issuer", Type: "string", Priority: 1},
+	{JSONPath: ".status.reason", Name: "Reason", Type: "string", Priority: 1},
+	ageColumn,
+}
+
+var challengeColumns = []apiext.CustomResourceColumnDefinition{
+	{JSONPath: ".status.state", Name: "State", Type: "string"},
+	{JSONPath: ".spec.dnsName", Name: "Domain", Type: "string"},
+	{JSONPath: ".status.reason", Name: "Reason", Type: "string", Priority: 1},
+	ageColumn,
+}
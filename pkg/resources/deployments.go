@@ -19,37 +19,110 @@ package resources
 import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-// ControllerDeployment is the deployment template for deploying the cert-manager-controller
-var ControllerDeployment = &appsv1.Deployment{
+// ConfigmapWatcherDeployment returns the deployment for the configmap watcher in the given namespace
+func ConfigmapWatcherDeployment(ns string) *appsv1.Deployment {
+	return deployment(configmapWatcherDeployment, ns)
+}
+
+// Returns a copy of the deployment template that shares no state with it
+func deployment(template *appsv1.Deployment, ns string) *appsv1.Deployment {
+	deploy := template.DeepCopy()
+	deploy.Namespace = ns
+	return deploy
+}
+
+// SetWebhookHostNetwork runs the webhook deployment on the host network or on the pod network.
+// On OpenShift the host network is only allowed by the hostnetwork security context constraint.
+func SetWebhookHostNetwork(deploy *appsv1.Deployment, hostNetwork bool) {
+	spec := &deploy.Spec.Template.Spec
+	spec.HostNetwork = hostNetwork
+	annotations := make(map[string]string, len(deploy.Spec.Template.Annotations))
+	for k, v := range deploy.Spec.Template.Annotations {
+		annotations[k] = v
+	}
+	if hostNetwork {
+		// Cluster DNS is only used on the host network when asked for
+		spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+		annotations[sccAnnotation] = "hostnetwork"
+	} else {
+		spec.DNSPolicy = corev1.DNSClusterFirst
+		annotations[sccAnnotation] = "restricted"
+	}
+	deploy.Spec.Template.Annotations = annotations
+}
+
+// SetReplicas scales the deployment. When it runs more than one replica and has no affinity of its
+// own, its pods prefer to be scheduled on different nodes.
+func SetReplicas(deploy *appsv1.Deployment, replicas int32) {
+	deploy.Spec.Replicas = &replicas
+	spec := &deploy.Spec.Template.Spec
+	if replicas < 2 || spec.Affinity != nil {
+		return
+	}
+	spec.Affinity = &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: corev1.PodAffinityTerm{
+						LabelSelector: deploy.Spec.Selector.DeepCopy(),
+						TopologyKey:   hostnameTopologyKey,
+					},
+				},
+			},
+		},
+	}
+}
+
+// PodDisruptionBudget returns the disruption budget that keeps at least one of the deployment's pods running
+func PodDisruptionBudget(deploy *appsv1.Deployment) *policyv1beta1.PodDisruptionBudget {
+	minAvailable := intstr.FromInt(1)
+	labels := make(map[string]string, len(deploy.Labels))
+	for k, v := range deploy.Labels {
+		labels[k] = v
+	}
+	return &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deploy.Name,
+			Namespace: deploy.Namespace,
+			Labels:    labels,
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector:     deploy.Spec.Selector.DeepCopy(),
+		},
+	}
+}
+
+var controllerDeployment = &appsv1.Deployment{
 	ObjectMeta: metav1.ObjectMeta{
-		Name: CertManagerControllerName,
-		//		Namespace: DeployNamespace,
-		Labels: ControllerLabelMap,
+		Name:   CertManagerControllerName,
+		Labels: controllerLabelMap,
 	},
 	Spec: appsv1.DeploymentSpec{
 		Replicas: &replicaCount,
 		Selector: &metav1.LabelSelector{
-			MatchLabels: ControllerLabelMap,
+			MatchLabels: controllerLabelMap,
 		},
 		Template: corev1.PodTemplateSpec{
 			ObjectMeta: metav1.ObjectMeta{
-				Labels:      ControllerLabelMap,
-				Annotations: PodAnnotations,
+				Labels:      controllerLabelMap,
+				Annotations: podAnnotations,
 			},
 			Spec: certManagerControllerPod,
 		},
 	},
 }
 
-// WebhookDeployment is the deployment template for deploying the cert-manager-webhook
-var WebhookDeployment = &appsv1.Deployment{
+var webhookDeployment = &appsv1.Deployment{
 	ObjectMeta: metav1.ObjectMeta{
-		Name: CertManagerWebhookName,
-		//		Namespace:   DeployNamespace,
-		Labels:      WebhookLabelMap,
+		Name:        CertManagerWebhookName,
+		Labels:      webhookLabelMap,
 		Annotations: webhookAnnotation,
 	},
 	Spec: appsv1.DeploymentSpec{
@@ -61,7 +134,7 @@ var WebhookDeployment = &appsv1.Deployment{
 		},
 		Template: corev1.PodTemplateSpec{
 			ObjectMeta: metav1.ObjectMeta{
-				Labels:      WebhookLabelMap,
+				Labels:      webhookLabelMap,
 				Annotations: securityAnnotationWebhook,
 			},
 			Spec: certManagerWebhookPod,
@@ -69,44 +142,40 @@ var WebhookDeployment = &appsv1.Deployment{
 	},
 }
 
-// CainjectorDeployment is the deployment template for deploying the cert-manager-cainjector
-var CainjectorDeployment = &appsv1.Deployment{
+var cainjectorDeployment = &appsv1.Deployment{
 	ObjectMeta: metav1.ObjectMeta{
-		Name: CertManagerCainjectorName,
-		//		Namespace: DeployNamespace,
-		Labels: CainjectorLabelMap,
+		Name:   CertManagerCainjectorName,
+		Labels: cainjectorLabelMap,
 	},
 	Spec: appsv1.DeploymentSpec{
 		Replicas: &replicaCount,
 		Selector: &metav1.LabelSelector{
-			MatchLabels: CainjectorLabelMap,
+			MatchLabels: cainjectorLabelMap,
 		},
 		Template: corev1.PodTemplateSpec{
 			ObjectMeta: metav1.ObjectMeta{
-				Labels:      CainjectorLabelMap,
-				Annotations: PodAnnotations,
+				Labels:      cainjectorLabelMap,
+				Annotations: podAnnotations,
 			},
 			Spec: certManagerCainjectorPod,
 		},
 	},
 }
 
-// ConfigmapWatcherDeployment is the deployment spec for the configmap watcher
-var ConfigmapWatcherDeployment = &appsv1.Deployment{
+var configmapWatcherDeployment = &appsv1.Deployment{
 	ObjectMeta: metav1.ObjectMeta{
-		Name: ConfigmapWatcherName,
-		//		Namespace: DeployNamespace,
-		Labels: ConfigmapWatcherLabelMap,
+		Name:   ConfigmapWatcherName,
+		Labels: configmapWatcherLabelMap,
 	},
 	Spec: appsv1.DeploymentSpec{
 		Replicas: &replicaCount,
 		Selector: &metav1.LabelSelector{
-			MatchLabels: ConfigmapWatcherLabelMap,
+			MatchLabels: configmapWatcherLabelMap,
 		},
 		Template: corev1.PodTemplateSpec{
 			ObjectMeta: metav1.ObjectMeta{
-				Labels:      ConfigmapWatcherLabelMap,
-				Annotations: PodAnnotations,
+				Labels:      configmapWatcherLabelMap,
+				Annotations: podAnnotations,
 			},
 			Spec: configmapWatcherPod,
 		},
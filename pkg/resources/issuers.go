@@ -0,0 +1,97 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package resources
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// SelfSignedIssuerName is the name of the self-signed clusterissuer that issues the root CA
+const SelfSignedIssuerName = "cs-ss-issuer"
+
+// CACertificateName is the name of the root CA certificate
+const CACertificateName = "cs-ca-certificate"
+
+// CASecretName is the secret the root CA is issued into
+const CASecretName = "cs-ca-certificate-secret"
+
+// CAIssuerName is the default name of the clusterissuer signing with the root CA
+const CAIssuerName = "cs-ca-issuer"
+
+// DefaultCADuration is how long the root CA is valid for by default
+var DefaultCADuration = metav1.Duration{Duration: 2 * 365 * 24 * time.Hour}
+
+// DefaultIssuerLabel marks the issuers and certificates the operator creates from the CertManager's defaultIssuers
+const DefaultIssuerLabel = "operator.ibm.com/default-issuer"
+
+// ClusterIssuerKind is the kind of cert-manager clusterissuers
+const ClusterIssuerKind = "ClusterIssuer"
+
+// CertificateKind is the kind of cert-manager certificates
+const CertificateKind = "Certificate"
+
+// Returns a default issuer or certificate of the kind in the bundle's API
+func defaultIssuerObject(bundle *Bundle, kind, name, ns string, spec map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	obj.SetAPIVersion(bundle.Group + "/" + bundle.APIVersion)
+	obj.SetKind(kind)
+	obj.SetName(name)
+	obj.SetNamespace(ns)
+	obj.SetLabels(map[string]string{DefaultIssuerLabel: "true"})
+	return obj
+}
+
+// SelfSignedIssuer returns the self-signed clusterissuer that issues the root CA
+func SelfSignedIssuer(bundle *Bundle) *unstructured.Unstructured {
+	return defaultIssuerObject(bundle, ClusterIssuerKind, SelfSignedIssuerName, "", map[string]interface{}{
+		"selfSigned": map[string]interface{}{},
+	})
+}
+
+// CACertificate returns the root CA certificate in the cluster resource namespace, which is where
+// cert-manager reads the secrets of clusterissuers from
+func CACertificate(bundle *Bundle, resourceNS, commonName string, duration metav1.Duration) *unstructured.Unstructured {
+	return defaultIssuerObject(bundle, CertificateKind, CACertificateName, resourceNS, map[string]interface{}{
+		"secretName": CASecretName,
+		"commonName": commonName,
+		"isCA":       true,
+		"duration":   duration.Duration.String(),
+		"issuerRef": map[string]interface{}{
+			"name": SelfSignedIssuerName,
+			"kind": ClusterIssuerKind,
+		},
+	})
+}
+
+// CAIssuer returns the clusterissuer signing with the root CA
+func CAIssuer(bundle *Bundle, name string) *unstructured.Unstructured {
+	return defaultIssuerObject(bundle, ClusterIssuerKind, name, "", map[string]interface{}{
+		"ca": map[string]interface{}{
+			"secretName": CASecretName,
+		},
+	})
+}
+
+// ACMEIssuer returns an ACME clusterissuer with the given ACME configuration
+func ACMEIssuer(bundle *Bundle, name string, acme map[string]interface{}) *unstructured.Unstructured {
+	return defaultIssuerObject(bundle, ClusterIssuerKind, name, "", map[string]interface{}{
+		"acme": acme,
+	})
+}
@@ -0,0 +1,182 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package resources
+
+import (
+	"fmt"
+
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ControllerMetricsName is the name of cert-manager-controller's metrics service, ServiceMonitor and PrometheusRule
+const ControllerMetricsName = CertManagerControllerName + "-metrics"
+
+// controllerMetricsPort is the port cert-manager-controller serves its metrics at in every release
+const controllerMetricsPort = 9402
+
+const controllerMetricsPortName = "metrics"
+
+// ControllerMetricsSvc returns the service exposing cert-manager-controller's metrics in the given namespace
+func ControllerMetricsSvc(ns string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ControllerMetricsName,
+			Namespace: ns,
+			Labels:    controllerLabelMap,
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name:       controllerMetricsPortName,
+					Port:       controllerMetricsPort,
+					Protocol:   corev1.ProtocolTCP,
+					TargetPort: intstr.FromInt(controllerMetricsPort),
+				},
+			},
+			Selector: controllerLabelMap,
+			Type:     corev1.ServiceTypeClusterIP,
+		},
+	}
+}
+
+// ControllerServiceMonitor returns the ServiceMonitor that has Prometheus scrape cert-manager-controller's metrics
+func ControllerServiceMonitor(ns string) *monitoringv1.ServiceMonitor {
+	return &monitoringv1.ServiceMonitor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ControllerMetricsName,
+			Namespace: ns,
+			Labels:    controllerLabelMap,
+		},
+		Spec: monitoringv1.ServiceMonitorSpec{
+			Endpoints: []monitoringv1.Endpoint{
+				{
+					Port:     controllerMetricsPortName,
+					Path:     "/metrics",
+					Interval: "60s",
+					// The metrics carry the namespace of the certificate, which must not be replaced by the service's
+					HonorLabels: true,
+				},
+			},
+			Selector:          metav1.LabelSelector{MatchLabels: controllerLabelMap},
+			NamespaceSelector: monitoringv1.NamespaceSelector{MatchNames: []string{ns}},
+		},
+	}
+}
+
+// prometheusRuleLabels are the labels the rule selectors of common Prometheus deployments look for
+var prometheusRuleLabels = map[string]string{
+	"prometheus": "k8s",
+	"role":       "alert-rules",
+}
+
+// PrometheusRule returns the alerts on cert-manager deployed from the bundle in the given namespace.
+// The readiness of certificates is only exported by cert-manager 1.5.4, and by the operator when
+// operatorMetrics is set, which also counts failed issuances. The webhook is only alerted on when it is deployed.
+// It is told to be unavailable by kube-state-metrics.
+func PrometheusRule(bundle *Bundle, ns string, webhook, operatorMetrics bool) *monitoringv1.PrometheusRule {
+	labels := make(map[string]string, len(controllerLabelMap)+len(prometheusRuleLabels))
+	for k, v := range controllerLabelMap {
+		labels[k] = v
+	}
+	for k, v := range prometheusRuleLabels {
+		labels[k] = v
+	}
+
+	rules := []monitoringv1.Rule{
+		{
+			Alert:  "CertManagerControllerDown",
+			Expr:   intstr.FromString(fmt.Sprintf(`absent(up{job="%s",namespace="%s"} == 1)`, ControllerMetricsName, ns)),
+			For:    "10m",
+			Labels: map[string]string{"severity": "critical"},
+			Annotations: map[string]string{
+				"summary":     "cert-manager-controller is down",
+				"description": "cert-manager-controller hasn't been scraped for 10 minutes. Certificates are not issued or renewed while it is down.",
+			},
+		},
+		{
+			Alert:  "CertManagerCertificateExpiringSoon",
+			Expr:   intstr.FromString(`certmanager_certificate_expiration_timestamp_seconds - time() < 7 * 24 * 3600`),
+			For:    "1h",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "Certificate {{ $labels.namespace }}/{{ $labels.name }} expires in less than a week",
+				"description": "The certificate expires in {{ $value | humanizeDuration }} and hasn't been renewed.",
+			},
+		},
+	}
+	if bundle.Version != LegacyVersion {
+		rules = append(rules, monitoringv1.Rule{
+			Alert:  "CertManagerCertificateNotReady",
+			Expr:   intstr.FromString(`max by (namespace, name) (certmanager_certificate_ready_status{condition="False"} == 1)`),
+			For:    "15m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "Certificate {{ $labels.namespace }}/{{ $labels.name }} is not ready",
+				"description": "The certificate has not been ready for 15 minutes, its issuance is failing.",
+			},
+		})
+	} else if operatorMetrics {
+		// The operator's metrics are scraped without honoring their labels, so the certificate's namespace is renamed
+		rules = append(rules, monitoringv1.Rule{
+			Alert:  "CertManagerCertificateNotReady",
+			Expr:   intstr.FromString(`max by (exported_namespace, name) (certmanager_operator_certificate_ready_status{condition="False"} == 1)`),
+			For:    "15m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "Certificate {{ $labels.exported_namespace }}/{{ $labels.name }} is not ready",
+				"description": "The certificate has not been ready for 15 minutes, its issuance is failing.",
+			},
+		})
+	}
+	if operatorMetrics {
+		rules = append(rules, monitoringv1.Rule{
+			Alert:  "CertManagerCertificateIssuanceFailing",
+			Expr:   intstr.FromString(`increase(certmanager_operator_certificate_issuance_failures_total[1h]) > 0`),
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "Issuance of certificate {{ $labels.exported_namespace }}/{{ $labels.name }} is failing",
+				"description": "cert-manager failed to issue the certificate in the last hour.",
+			},
+		})
+	}
+	if webhook {
+		rules = append(rules, monitoringv1.Rule{
+			Alert:  "CertManagerWebhookUnavailable",
+			Expr:   intstr.FromString(fmt.Sprintf(`kube_deployment_status_replicas_available{namespace="%s",deployment="%s"} == 0`, ns, CertManagerWebhookName)),
+			For:    "5m",
+			Labels: map[string]string{"severity": "critical"},
+			Annotations: map[string]string{
+				"summary":     "cert-manager-webhook is unavailable",
+				"description": "cert-manager-webhook has had no available replicas for 5 minutes. Certificates and issuers can't be created or changed while it is unavailable.",
+			},
+		})
+	}
+
+	return &monitoringv1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ControllerMetricsName,
+			Namespace: ns,
+			Labels:    labels,
+		},
+		Spec: monitoringv1.PrometheusRuleSpec{
+			Groups: []monitoringv1.RuleGroup{{Name: "cert-manager", Rules: rules}},
+		},
+	}
+}
@@ -0,0 +1,181 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package resources
+
+import (
+	"fmt"
+
+	admRegv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// OperatorName is the name of the operator's deployment, and of the label that selects its pods
+const OperatorName = "ibm-cert-manager-operator"
+
+// OperatorWebhookName is the name of the operator's webhook configurations, service and serving secret
+const OperatorWebhookName = OperatorName + "-webhook"
+
+// OperatorWebhookPort is the port the operator serves its webhook at
+const OperatorWebhookPort = 9443
+
+// OperatorMutatePath is the path the operator serves the defaulting of CertManagers at
+const OperatorMutatePath = "/mutate-operator-ibm-com-v1alpha1-certmanager"
+
+// OperatorValidatePath is the path the operator serves the validation of CertManagers at
+const OperatorValidatePath = "/validate-operator-ibm-com-v1alpha1-certmanager"
+
+// OperatorSolverPodPath is the path the operator serves the injection of the proxy into the acmesolver pods at
+const OperatorSolverPodPath = "/mutate-v1-pod-acmesolver"
+
+// AcmesolverLabels are the labels cert-manager sets to true on the acmesolver pods, in each of its releases
+var AcmesolverLabels = []string{GroupVersion + "/acme-http01-solver", "acme." + V1Group + "/http01-solver"}
+
+// The operator's webhook is ignored when it can't be reached, so that the CertManager
+// can still be edited, and its finalizer removed, while the operator isn't running
+var operatorWebhookFailurePolicy = admRegv1beta1.Ignore
+var operatorWebhookTimeout int32 = 10
+
+var operatorWebhookLabelMap = map[string]string{"app": OperatorWebhookName}
+
+// Returns the rules that send only the CertManagers to the operator
+func operatorWebhookRules() []admRegv1beta1.RuleWithOperations {
+	scope := admRegv1beta1.ClusterScope
+	return []admRegv1beta1.RuleWithOperations{
+		{
+			Operations: []admRegv1beta1.OperationType{admRegv1beta1.Create, admRegv1beta1.Update},
+			Rule: admRegv1beta1.Rule{
+				APIGroups:   []string{"operator.ibm.com"},
+				APIVersions: []string{"v1alpha1"},
+				Resources:   []string{"certmanagers"},
+				Scope:       &scope,
+			},
+		},
+	}
+}
+
+// OperatorWebhookSvc returns the service of the operator's webhook in the given namespace
+func OperatorWebhookSvc(ns string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      OperatorWebhookName,
+			Namespace: ns,
+			Labels:    operatorWebhookLabelMap,
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "https",
+					Port:       443,
+					Protocol:   corev1.ProtocolTCP,
+					TargetPort: intstr.FromInt(OperatorWebhookPort),
+				},
+			},
+			Selector: map[string]string{"name": OperatorName},
+			Type:     corev1.ServiceTypeClusterIP,
+		},
+	}
+}
+
+// Returns the rules that send the pods that are created to the operator
+func operatorSolverPodRules() []admRegv1beta1.RuleWithOperations {
+	scope := admRegv1beta1.NamespacedScope
+	return []admRegv1beta1.RuleWithOperations{
+		{
+			Operations: []admRegv1beta1.OperationType{admRegv1beta1.Create},
+			Rule: admRegv1beta1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+				Scope:       &scope,
+			},
+		},
+	}
+}
+
+// OperatorMutatingWebhook returns the configuration of the operator's webhook that fills in the
+// defaults of CertManagers and injects the proxy into the acmesolver pods. It is served in the given
+// namespace with a certificate signed by the CA bundle. Only the acmesolver pods are sent to it, by
+// one webhook for the label of each cert-manager release.
+func OperatorMutatingWebhook(ns string, caBundle []byte) *admRegv1beta1.MutatingWebhookConfiguration {
+	path := OperatorMutatePath
+	webhooks := []admRegv1beta1.MutatingWebhook{
+		{
+			Name:                    "mcertmanager.operator.ibm.com",
+			ClientConfig:            operatorWebhookClientConfig(ns, path, caBundle),
+			Rules:                   operatorWebhookRules(),
+			FailurePolicy:           &operatorWebhookFailurePolicy,
+			SideEffects:             &sideEffect,
+			TimeoutSeconds:          &operatorWebhookTimeout,
+			AdmissionReviewVersions: []string{"v1beta1"},
+		},
+	}
+	for i, label := range AcmesolverLabels {
+		webhooks = append(webhooks, admRegv1beta1.MutatingWebhook{
+			Name:                    fmt.Sprintf("macmesolver%d.operator.ibm.com", i),
+			ClientConfig:            operatorWebhookClientConfig(ns, OperatorSolverPodPath, caBundle),
+			Rules:                   operatorSolverPodRules(),
+			FailurePolicy:           &operatorWebhookFailurePolicy,
+			SideEffects:             &sideEffect,
+			TimeoutSeconds:          &operatorWebhookTimeout,
+			ObjectSelector:          &metav1.LabelSelector{MatchLabels: map[string]string{label: "true"}},
+			AdmissionReviewVersions: []string{"v1beta1"},
+		})
+	}
+	return &admRegv1beta1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   OperatorWebhookName,
+			Labels: operatorWebhookLabelMap,
+		},
+		Webhooks: webhooks,
+	}
+}
+
+// OperatorValidatingWebhook returns the configuration of the operator's webhook that validates
+// CertManagers. It is served in the given namespace with a certificate signed by the CA bundle.
+func OperatorValidatingWebhook(ns string, caBundle []byte) *admRegv1beta1.ValidatingWebhookConfiguration {
+	path := OperatorValidatePath
+	return &admRegv1beta1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   OperatorWebhookName,
+			Labels: operatorWebhookLabelMap,
+		},
+		Webhooks: []admRegv1beta1.ValidatingWebhook{
+			{
+				Name:                    "vcertmanager.operator.ibm.com",
+				ClientConfig:            operatorWebhookClientConfig(ns, path, caBundle),
+				Rules:                   operatorWebhookRules(),
+				FailurePolicy:           &operatorWebhookFailurePolicy,
+				SideEffects:             &sideEffect,
+				TimeoutSeconds:          &operatorWebhookTimeout,
+				AdmissionReviewVersions: []string{"v1beta1"},
+			},
+		},
+	}
+}
+
+func operatorWebhookClientConfig(ns, path string, caBundle []byte) admRegv1beta1.WebhookClientConfig {
+	return admRegv1beta1.WebhookClientConfig{
+		Service: &admRegv1beta1.ServiceReference{
+			Namespace: ns,
+			Name:      OperatorWebhookName,
+			Path:      &path,
+		},
+		CABundle: caBundle,
+	}
+}
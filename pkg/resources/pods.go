@@ -33,7 +33,7 @@ var certManagerControllerPod = corev1.PodSpec{
 }
 
 var certManagerWebhookPod = corev1.PodSpec{
-	HostNetwork:        TrueVar,
+	HostNetwork:        trueVar,
 	ServiceAccountName: ServiceAccount,
 	SecurityContext:    podSecurity,
 	Containers: []corev1.Container{
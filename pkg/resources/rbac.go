@@ -22,16 +22,33 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// DefaultServiceAccount is the service account used by cert-manager service
-var DefaultServiceAccount = &corev1.ServiceAccount{
-	ObjectMeta: metav1.ObjectMeta{
-		Name: ServiceAccount,
-		//		Namespace: DeployNamespace,
-	},
+// DefaultServiceAccount returns the service account used by cert-manager service in the given namespace
+func DefaultServiceAccount(ns string) *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ServiceAccount,
+			Namespace: ns,
+		},
+	}
+}
+
+// DefaultClusterRoleBinding returns the clusterrolebinding used by cert-manager service,
+// binding the cluster role to the service account in the given namespace
+func DefaultClusterRoleBinding(ns string) *rbacv1.ClusterRoleBinding {
+	binding := defaultClusterRoleBinding.DeepCopy()
+	binding.Subjects[0].Namespace = ns
+	return binding
 }
 
-// DefaultClusterRole is the cluster role used by cert-manager service
-var DefaultClusterRole = &rbacv1.ClusterRole{
+// WebhookRoleBinding returns the rolebinding used for the cert-manager-webhook's ability to read the
+// extension-apiserver-authentication, bound to the service account in the given namespace
+func WebhookRoleBinding(ns string) *rbacv1.RoleBinding {
+	binding := webhookRoleBinding.DeepCopy()
+	binding.Subjects[0].Namespace = ns
+	return binding
+}
+
+var defaultClusterRole = &rbacv1.ClusterRole{
 	ObjectMeta: metav1.ObjectMeta{
 		Name: ClusterRoleName,
 	},
@@ -116,8 +133,7 @@ var DefaultClusterRole = &rbacv1.ClusterRole{
 	},
 }
 
-// DefaultClusterRoleBinding the clusterrolebinding used by cert-manager service
-var DefaultClusterRoleBinding = &rbacv1.ClusterRoleBinding{
+var defaultClusterRoleBinding = &rbacv1.ClusterRoleBinding{
 	ObjectMeta: metav1.ObjectMeta{
 		Name: ClusterRoleName,
 	},
@@ -126,7 +142,6 @@ var DefaultClusterRoleBinding = &rbacv1.ClusterRoleBinding{
 			Kind:     "ServiceAccount",
 			APIGroup: "",
 			Name:     ServiceAccount,
-			//			Namespace: DeployNamespace,
 		},
 	},
 	RoleRef: rbacv1.RoleRef{
@@ -136,18 +151,16 @@ var DefaultClusterRoleBinding = &rbacv1.ClusterRoleBinding{
 	},
 }
 
-// WebhookRoleBinding is the rolebinding used for the cert-manager-webhook's ability to read the extension-apiserver-authentication
-var WebhookRoleBinding = &rbacv1.RoleBinding{
+var webhookRoleBinding = &rbacv1.RoleBinding{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:      CertManagerWebhookName,
 		Namespace: "kube-system",
 	},
 	Subjects: []rbacv1.Subject{
 		{
-			Kind:      "ServiceAccount",
-			APIGroup:  "",
-			Name:      ServiceAccount,
-			Namespace: DeployNamespace,
+			Kind:     "ServiceAccount",
+			APIGroup: "",
+			Name:     ServiceAccount,
 		},
 	},
 	RoleRef: rbacv1.RoleRef{
@@ -29,11 +29,35 @@ var mutationPath = "/apis/webhook.certmanager.k8s.io/v1beta1/mutations"
 var failPolicy = admRegv1beta1.Fail
 var sideEffect = admRegv1beta1.SideEffectClassNone
 
-// MutatingWebhook is the mutating webhook definition for cert-manager-webhook
-var MutatingWebhook = &admRegv1beta1.MutatingWebhookConfiguration{
+// legacyWebhookTimeout is the default timeout of admissionregistration v1beta1, which the legacy webhook was written for
+var legacyWebhookTimeout int32 = 30
+
+// Returns the validating webhook definition for the legacy cert-manager-webhook
+func legacyValidatingWebhook(ns string) *admRegv1beta1.ValidatingWebhookConfiguration {
+	webhook := validatingWebhook.DeepCopy()
+	webhook.Webhooks[0].NamespaceSelector.MatchExpressions[1].Values = []string{ns}
+	return webhook
+}
+
+// APIService returns the apiservice for cert-manager-webhook, served by the webhook's service in the given namespace
+func APIService(ns string) *apiRegv1.APIService {
+	apiSvc := apiService.DeepCopy()
+	apiSvc.Annotations = map[string]string{"certmanager.k8s.io/inject-ca-from-secret": ns + "/" + WebhookServingSecret}
+	apiSvc.Spec.Service.Namespace = ns
+	return apiSvc
+}
+
+// Returns the service definition for the legacy cert-manager-webhook in the given namespace
+func legacyWebhookSvc(ns string) *corev1.Service {
+	svc := webhookSvc.DeepCopy()
+	svc.Namespace = ns
+	return svc
+}
+
+var mutatingWebhook = &admRegv1beta1.MutatingWebhookConfiguration{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:   CertManagerWebhookName,
-		Labels: WebhookLabelMap,
+		Labels: webhookLabelMap,
 		Annotations: map[string]string{
 			"certmanager.k8s.io/inject-apiserver-ca": "true",
 		},
@@ -48,6 +72,10 @@ var MutatingWebhook = &admRegv1beta1.MutatingWebhookConfiguration{
 					Path:      &mutationPath,
 				},
 			},
+			SideEffects:    &sideEffect,
+			TimeoutSeconds: &legacyWebhookTimeout,
+			// The legacy webhook only understands v1beta1 admission reviews
+			AdmissionReviewVersions: []string{"v1beta1"},
 			Rules: []admRegv1beta1.RuleWithOperations{
 				{
 					Operations: []admRegv1beta1.OperationType{
@@ -77,21 +105,15 @@ var MutatingWebhook = &admRegv1beta1.MutatingWebhookConfiguration{
 	},
 }
 
-//const injectSecretCA = DeployNamespace + "/" + webhookServingSecret
-
 // APISvcName is the name used for cert-manager-webhooks' apiservice definition
 const APISvcName = "v1beta1.webhook.certmanager.k8s.io"
 
-// APIService is the apiservice for cert-manager-webhook
-var APIService = &apiRegv1.APIService{
+var apiService = &apiRegv1.APIService{
 	ObjectMeta: metav1.ObjectMeta{
 		Name: APISvcName,
 		Labels: map[string]string{
 			"app": "ibm-cert-manager-webhook",
 		},
-		Annotations: map[string]string{
-			//"certmanager.k8s.io/inject-ca-from-secret": injectSecretCA,
-		},
 	},
 	Spec: apiRegv1.APIServiceSpec{
 		Group:                "webhook.certmanager.k8s.io",
@@ -99,17 +121,14 @@ var APIService = &apiRegv1.APIService{
 		VersionPriority:      15,
 		Service: &apiRegv1.ServiceReference{
 			Name: CertManagerWebhookName,
-			//Namespace: DeployNamespace,
 		},
 		Version: "v1beta1",
 	},
 }
 
-// WebhookSvc is the service definition for cert-manager-webhook
-var WebhookSvc = &corev1.Service{
+var webhookSvc = &corev1.Service{
 	ObjectMeta: metav1.ObjectMeta{
 		Name: CertManagerWebhookName,
-		//Namespace: DeployNamespace,
 		Labels: map[string]string{
 			"app": "ibm-cert-manager-webhook",
 		},
@@ -131,11 +150,10 @@ var WebhookSvc = &corev1.Service{
 	},
 }
 
-// ValidatingWebhook is the validating webhook definition for cert-manager-webhook
-var ValidatingWebhook = &admRegv1beta1.ValidatingWebhookConfiguration{
+var validatingWebhook = &admRegv1beta1.ValidatingWebhookConfiguration{
 	ObjectMeta: metav1.ObjectMeta{
 		Name:   CertManagerWebhookName,
-		Labels: WebhookLabelMap,
+		Labels: webhookLabelMap,
 		Annotations: map[string]string{
 			"certmanager.k8s.io/inject-apiserver-ca": "true",
 		},
@@ -172,8 +190,10 @@ var ValidatingWebhook = &admRegv1beta1.ValidatingWebhookConfiguration{
 					Path:      &valPath,
 				},
 			},
-			FailurePolicy: &failPolicy,
-			SideEffects:   &sideEffect,
+			FailurePolicy:           &failPolicy,
+			SideEffects:             &sideEffect,
+			TimeoutSeconds:          &legacyWebhookTimeout,
+			AdmissionReviewVersions: []string{"v1beta1"},
 			NamespaceSelector: &metav1.LabelSelector{
 				MatchExpressions: []metav1.LabelSelectorRequirement{
 					{
@@ -184,7 +204,6 @@ var ValidatingWebhook = &admRegv1beta1.ValidatingWebhookConfiguration{
 					{
 						Key:      "name",
 						Operator: metav1.LabelSelectorOpNotIn,
-						Values:   []string{DeployNamespace},
 					},
 				},
 			},